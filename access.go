@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// grantTeamMaintain gives the org team identified by slug maintain permission
+// on repo and makes sure user keeps admin, so that the team can run the
+// repository day to day while its creator can still administer it. It
+// returns a description of the resulting permissions, or an unchangedStep
+// error if both were already in place.
+func grantTeamMaintain(ctx context.Context, client *github.Client, org, slug string, repo *github.Repository, user string) (string, error) {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	slug = strings.TrimPrefix(slug, org+"/")
+	changed := false
+
+	// A team without access to the repository is reported as 404
+	current, resp, err := client.Teams.IsTeamRepoBySlug(ctx, org, slug, owner, name)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return "", fmt.Errorf("failed to check access of team %s: %w", slug, err)
+	}
+	if current.GetRoleName() != "maintain" {
+		_, err := client.Teams.AddTeamRepoBySlug(ctx, org, slug, owner, name, &github.TeamAddTeamRepoOptions{Permission: "maintain"})
+		if err != nil {
+			return "", fmt.Errorf("failed to give team %s maintain access: %w", slug, err)
+		}
+		changed = true
+	}
+
+	level, _, err := client.Repositories.GetPermissionLevel(ctx, owner, name, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permissions of %s: %w", user, err)
+	}
+	if level.GetPermission() != "admin" {
+		_, _, err := client.Repositories.AddCollaborator(ctx, owner, name, user, &github.RepositoryAddCollaboratorOptions{Permission: "admin"})
+		if err != nil {
+			return "", fmt.Errorf("failed to give %s admin access: %w", user, err)
+		}
+		changed = true
+	}
+
+	detail := fmt.Sprintf("%s/%s: maintain, %s: admin", org, slug, user)
+	if !changed {
+		return "", unchangedStep(detail)
+	}
+	return detail, nil
+}
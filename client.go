@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+
+	"github.com/google/go-github/v57/github"
+	"golang.org/x/oauth2"
+)
+
+// version is the repoinit version. Release builds set it with
+// -ldflags "-X main.version=v1.2.3"; otherwise the module version recorded by
+// go install is used.
+var version = ""
+
+// userAgent is sent with every request to GitHub so the tool's traffic can be
+// told apart in audit logs.
+var userAgent = "repoinit/" + buildVersion()
+
+// buildVersion returns the version repoinit was built as.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// configureUserAgent overrides userAgent with flagValue (-user-agent) or,
+// failing that, REPOINIT_USER_AGENT.
+func configureUserAgent(flagValue string) {
+	if flagValue == "" {
+		flagValue = os.Getenv("REPOINIT_USER_AGENT")
+	}
+	if flagValue != "" {
+		userAgent = flagValue
+	}
+}
+
+// newGitHubClient returns a GitHub API client for the configured GitHub
+// instance, authenticated with token and sending its requests through
+// httpClient.
+func newGitHubClient(ctx context.Context, token string) *github.Client {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	client.UserAgent = userAgent
+	if githubAPIURL != "" {
+		// configureGitHubHost has already checked that the URLs parse
+		client, _ = client.WithEnterpriseURLs(githubAPIURL, githubServerURL)
+	}
+	return client
+}
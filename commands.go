@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// subcommands are dispatched on the first command line argument. Anything
+// else is treated as flags for the default repository setup.
+var subcommands = map[string]func(args []string) error{
+	"login":         runLogin,
+	"whoami":        runWhoami,
+	"migrate-token": runMigrateToken,
+}
+
+// errUsage is returned by subcommands for invalid command lines; the flag
+// package has already explained the problem.
+var errUsage = errors.New("invalid usage")
+
+func parseSubcommandFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return errUsage
+	}
+	return nil
+}
+
+// runLogin implements "repoinit login": it obtains a token through the gh CLI
+// or the OAuth device flow, stores it, and prints the account it belongs to.
+// A valid stored token is kept unless -force is given.
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("repoinit login", flag.ContinueOnError)
+	force := fs.Bool("force", false, "log in again even if a valid token is already stored")
+	deviceFlowTimeout := fs.Duration("device-flow-timeout", 0, "give up on device flow authorization after this long (default: GitHub's code expiry)")
+	if err := parseSubcommandFlags(fs, args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if !*force {
+		if token, _ := readStoredToken(); token != "" {
+			user, _, err := newGitHubClient(ctx, token).Users.Get(ctx, "")
+			if err == nil {
+				successf("Already logged in as %s (use -force to log in again)", user.GetLogin())
+				return nil
+			}
+			warnf("Stored token is no longer valid: %v", err)
+		}
+	}
+
+	token, source, err := loginToken(ctx, *deviceFlowTimeout, !*force)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	user, _, err := newGitHubClient(ctx, token).Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("login succeeded but the token was rejected: %w", err)
+	}
+	successf("Logged in as %s (via %s)", user.GetLogin(), source)
+	return nil
+}
+
+// runWhoami implements "repoinit whoami": it resolves a token without any
+// interactive login and prints the account, token source and scopes.
+func runWhoami(args []string) error {
+	opts := &options{}
+	fs := flag.NewFlagSet("repoinit whoami", flag.ContinueOnError)
+	fs.StringVar(&opts.tokenFromPass, "token-from-pass", "", "read the GitHub token from this pass `entry`")
+	fs.StringVar(&opts.tokenFromOp, "token-from-op", "", "read the GitHub token from this 1Password secret `reference`")
+	if err := parseSubcommandFlags(fs, args); err != nil {
+		return err
+	}
+
+	token, source, err := findGitHubToken(opts)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		if ghToken, err := tryGhToken(); err == nil {
+			token, source = ghToken, tokenSourceGh
+		}
+	}
+	if token == "" {
+		return errors.New("not logged in: no GitHub token found. Run `repoinit login` or set GITHUB_TOKEN")
+	}
+
+	ctx := context.Background()
+	user, resp, err := newGitHubClient(ctx, token).Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("the token from %s was rejected: %w", source, err)
+	}
+
+	scopes := resp.Header.Get("X-OAuth-Scopes")
+	if scopes == "" {
+		scopes = "(none reported; fine-grained tokens don't have scopes)"
+	}
+	fmt.Printf("Logged in as: %s\n", user.GetLogin())
+	fmt.Printf("Token source: %s\n", source)
+	fmt.Printf("Scopes:       %s\n", scopes)
+	return nil
+}
+
+// runMigrateToken implements "repoinit migrate-token": it copies the token gh
+// stored for the GitHub host (see tryGhStoredToken) into repoinit's own token
+// file, so repoinit keeps working where gh can't be run.
+func runMigrateToken(args []string) error {
+	fs := flag.NewFlagSet("repoinit migrate-token", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace a token repoinit has already stored")
+	if err := parseSubcommandFlags(fs, args); err != nil {
+		return err
+	}
+
+	if token, _ := readStoredToken(); token != "" && !*force {
+		return errors.New("repoinit already has a stored token; use -force to replace it")
+	}
+
+	token, location, err := tryGhStoredToken(githubHost())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	user, _, err := newGitHubClient(ctx, token).Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("the token from %s was rejected: %w", location, err)
+	}
+	if err := writeStoredToken(token); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	successf("Imported the token of %s from %s", user.GetLogin(), location)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import "regexp"
+
+// emojiShortcodes maps the GitHub emoji shortcodes most used in repository
+// descriptions to their Unicode characters, for -expand-emoji.
+var emojiShortcodes = map[string]string{
+	"+1":                       "👍",
+	"-1":                       "👎",
+	"art":                      "🎨",
+	"books":                    "📚",
+	"boom":                     "💥",
+	"bug":                      "🐛",
+	"bulb":                     "💡",
+	"chart_with_upwards_trend": "📈",
+	"check":                    "✔️",
+	"white_check_mark":         "✅",
+	"closed_lock_with_key":     "🔐",
+	"cloud":                    "☁️",
+	"computer":                 "💻",
+	"construction":             "🚧",
+	"crab":                     "🦀",
+	"earth_africa":             "🌍",
+	"earth_americas":           "🌎",
+	"earth_asia":               "🌏",
+	"fire":                     "🔥",
+	"gear":                     "⚙️",
+	"gem":                      "💎",
+	"globe_with_meridians":     "🌐",
+	"hammer":                   "🔨",
+	"hammer_and_wrench":        "🛠️",
+	"heart":                    "❤️",
+	"hourglass":                "⌛",
+	"key":                      "🔑",
+	"lock":                     "🔒",
+	"mag":                      "🔍",
+	"memo":                     "📝",
+	"package":                  "📦",
+	"penguin":                  "🐧",
+	"pencil":                   "📝",
+	"rainbow":                  "🌈",
+	"robot":                    "🤖",
+	"rocket":                   "🚀",
+	"shield":                   "🛡️",
+	"snake":                    "🐍",
+	"sparkles":                 "✨",
+	"star":                     "⭐",
+	"tada":                     "🎉",
+	"test_tube":                "🧪",
+	"warning":                  "⚠️",
+	"whale":                    "🐳",
+	"wrench":                   "🔧",
+	"zap":                      "⚡",
+}
+
+var emojiShortcodePattern = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// expandEmoji replaces the known :shortcode: emoji in s with their Unicode
+// characters. Unknown shortcodes are left as they are.
+func expandEmoji(s string) string {
+	return emojiShortcodePattern.ReplaceAllStringFunc(s, func(code string) string {
+		if emoji, ok := emojiShortcodes[code[1:len(code)-1]]; ok {
+			return emoji
+		}
+		return code
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// jsonErrors is set in -json mode, where a fatal error is written to stderr
+// as a JSON failure object instead of a log line.
+var jsonErrors bool
+
+// Failure codes reported in -json mode, so wrapping tools can tell the
+// categories apart.
+const (
+	failureAuth        = "auth"
+	failureSSO         = "sso"
+	failurePermission  = "permission"
+	failureNotFound    = "not_found"
+	failureInvalid     = "invalid_request"
+	failureRateLimited = "rate_limited"
+	failureNetwork     = "network"
+	failureGit         = "git"
+	failureCanceled    = "canceled"
+	failureOther       = "error"
+)
+
+// failure is the -json description of a failed run.
+type failure struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Hint  string `json:"hint,omitempty"`
+}
+
+// classifyError returns the failure code for err and, where there is an
+// obvious next step, a hint.
+func classifyError(err error) (code, hint string) {
+	if err == nil {
+		return failureOther, ""
+	}
+	if url, ok := ssoAuthorizationURL(err); ok {
+		if url == "" {
+			return failureSSO, "authorize the token for the organization's SAML single sign-on"
+		}
+		return failureSSO, "authorize the token for the organization's SAML single sign-on at " + url
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+		return failureRateLimited, "wait for the rate limit to reset (see -print-rate-limit) or raise -max-retries"
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		switch errResp.Response.StatusCode {
+		case http.StatusUnauthorized:
+			return failureAuth, "the token is invalid or expired; run `repoinit login -force`"
+		case http.StatusForbidden:
+			return failurePermission, "the token lacks a scope or the account lacks access"
+		case http.StatusNotFound:
+			return failureNotFound, "check the owner and repository names; private resources also show as not found without access"
+		case http.StatusUnprocessableEntity:
+			return failureInvalid, ""
+		}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return failureGit, ""
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return failureCanceled, ""
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return failureNetwork, "check the connection to GitHub, or retry with a higher -max-retries"
+	}
+	return failureOther, ""
+}
+
+// fatal reports a fatal error like log.Fatal, or as a JSON failure in -json
+// mode, classified by the last error among v.
+func fatal(v ...any) {
+	fail(fmt.Sprint(v...), v)
+}
+
+// fatalf is fatal with a format string.
+func fatalf(format string, v ...any) {
+	fail(fmt.Sprintf(format, v...), v)
+}
+
+func fail(message string, v []any) {
+	if !jsonErrors {
+		log.Output(3, message)
+		os.Exit(1)
+	}
+	var err error
+	for _, a := range v {
+		if e, ok := a.(error); ok {
+			err = e
+		}
+	}
+	code, hint := classifyError(err)
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetIndent("", "  ")
+	enc.Encode(failure{Error: message, Code: code, Hint: hint})
+	os.Exit(1)
+}
@@ -0,0 +1,870 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// options holds everything that can be configured from the command line.
+type options struct {
+	// reuse allows pushing into a repository that already exists.
+	reuse bool
+	// protectTags is a tag pattern to protect with a ruleset, e.g. "v*".
+	protectTags string
+	// autoMerge enables auto-merge for pull requests.
+	autoMerge bool
+	// squashTitle, squashMessage, mergeTitle and mergeMessage select the
+	// default commit title/message formats, as GitHub API enum values.
+	squashTitle   string
+	squashMessage string
+	mergeTitle    string
+	mergeMessage  string
+	// checkName only reports whether the repository name is available.
+	checkName bool
+	// deviceFlowTimeout caps how long the OAuth device flow waits for the
+	// user to authorize. Zero means use GitHub's expiry.
+	deviceFlowTimeout time.Duration
+	// showTokenSource logs which source provided the GitHub token.
+	showTokenSource bool
+	// org creates the repository under this organization instead of the
+	// authenticated user.
+	org string
+	// gitignoreTemplates are GitHub gitignore template names merged into
+	// .gitignore before staging.
+	gitignoreTemplates []string
+	// license is an SPDX license id; it is set as the repository license
+	// template and written to LICENSE for the initial commit.
+	license string
+	// dryRun previews the repository and initial commit without changing
+	// anything locally or on GitHub.
+	dryRun bool
+	// envExample is the dotenv file to turn into a committed .example copy.
+	envExample string
+	// noDefaultLabels deletes the labels GitHub adds to new repositories.
+	noDefaultLabels bool
+	// onSuccess is a shell command run after a successful push.
+	onSuccess string
+	// topics are applied to the repository after creation.
+	topics []string
+	// autoTopics adds topics detected from the files in the working tree.
+	autoTopics bool
+	// owner is the user or organization to create the repository for; which
+	// of the two it is gets looked up.
+	owner string
+	// color is auto, always or never.
+	color string
+	// tokenFromPass is a pass(1) entry holding the GitHub token.
+	tokenFromPass string
+	// tokenFromOp is a 1Password secret reference holding the GitHub token.
+	tokenFromOp string
+	// debugHTTP logs every HTTP request and response to stderr.
+	debugHTTP bool
+	// syncExisting rebases local commits onto an existing remote branch
+	// before pushing.
+	syncExisting bool
+	// teamMaintain is an org team slug given maintain access, with the
+	// creating user kept as admin.
+	teamMaintain string
+	// json prints a machine-readable result on stdout.
+	json bool
+	// renameExisting renames the local branch to an existing repository's
+	// default branch instead of failing on a mismatch.
+	renameExisting bool
+	// pages publishes the pushed branch with GitHub Pages.
+	pages bool
+	// homepageFromPages sets the repository homepage to the Pages URL.
+	homepageFromPages bool
+	// autoInit lets GitHub create the root commit (README, and LICENSE for
+	// -license); local files are committed on top of it.
+	autoInit bool
+	// refuseDirty aborts in a repository with commits if the working tree
+	// has uncommitted changes.
+	refuseDirty bool
+	// tagInitial tags the pushed commit and pushes the tag with the branch.
+	tagInitial string
+	// tagAnnotated makes tagInitial an annotated tag; tagMessage is its
+	// message and implies tagAnnotated.
+	tagAnnotated bool
+	tagMessage   string
+	// verbose prints extra progress details, such as the staged files.
+	verbose bool
+	// listFiles lists every staged file, however many there are.
+	listFiles bool
+	// print outputs only this repository field on stdout.
+	print string
+	// githubURL is the web URL of a GitHub Enterprise Server instance.
+	githubURL string
+	// noInitialCommit skips staging and committing; only the repository and
+	// remote are set up and the current branch is pushed.
+	noInitialCommit bool
+	// topicsReplace overwrites the repository's topics instead of adding to
+	// them.
+	topicsReplace bool
+	// userAgent overrides the User-Agent sent to GitHub.
+	userAgent string
+	// maxRetries and retryBaseDelay configure how failed GitHub requests
+	// and pushes are retried.
+	maxRetries     int
+	retryBaseDelay time.Duration
+	// insecureSkipTLSVerify disables TLS certificate verification for
+	// GitHub requests. For test servers only.
+	insecureSkipTLSVerify bool
+	// visibility is public, private or internal; empty means the default.
+	visibility string
+	// orgInternalDefault makes internal the default visibility for
+	// organization repositories.
+	orgInternalDefault bool
+	// commitMessage is the message of the initial commit; it is read from
+	// commitMessageFile if only that is given.
+	commitMessage     string
+	commitMessageFile string
+	// gitignoreMode is how gitignore templates combine with an existing
+	// .gitignore: keep, merge or overwrite.
+	gitignoreMode string
+	// printRateLimit prints the remaining API quota after the run.
+	printRateLimit bool
+	// protectionFrom is an owner/repo whose default branch protection is
+	// copied to the new repository's default branch.
+	protectionFrom string
+	// timings prints how long each phase of the run took.
+	timings bool
+	// manifest is a file listing exactly the paths to commit, in order;
+	// manifestPaths holds its entries once validated.
+	manifest      string
+	manifestPaths []string
+	// allowUnsigned commits without a signature if signing fails.
+	allowUnsigned bool
+	// since publishes only the history from this commit on.
+	since string
+	// description is the repository description.
+	description string
+	// noEditExisting leaves the settings of a reused repository alone.
+	noEditExisting bool
+	// coAuthors are added as Co-authored-by trailers to the initial commit.
+	coAuthors []string
+	// noVerifyPush skips checking that the remote branch matches what was
+	// pushed.
+	noVerifyPush bool
+	// template is an owner/repo template repository to generate the
+	// repository from.
+	template string
+	// templateExcludes are globs of template files to leave out.
+	templateExcludes []string
+	// changelog writes a Keep a Changelog style CHANGELOG.md.
+	changelog bool
+	// changelogTemplate is a file to use as CHANGELOG.md instead of the
+	// built-in one.
+	changelogTemplate string
+	// funding holds the platform:handle pairs written to
+	// .github/FUNDING.yml.
+	funding []fundingEntry
+	// subtree publishes only this directory, as the repository root.
+	subtree string
+	// labels are created on the repository; labelsFrom is a JSON file with
+	// more of them.
+	labels     []labelSpec
+	labelsFrom string
+	// labelsSync makes the repository's labels exactly match labels,
+	// updating and deleting existing ones.
+	labelsSync bool
+	// noEnvFile skips loading .env.
+	noEnvFile bool
+	// envFile is a dotenv file to load instead of .env.
+	envFile string
+	// stageMode selects what goes into the initial commit: all, tracked or
+	// interactive.
+	stageMode string
+	// pagesDomain is the custom domain of the Pages site.
+	pagesDomain string
+	// gitkeep adds a .gitkeep to every empty directory so it is committed.
+	gitkeep bool
+	// defaultOwner is the @org/team written as the default owner in
+	// .github/CODEOWNERS.
+	defaultOwner string
+	// protectDefault requires pull request reviews on the default branch.
+	protectDefault bool
+	// waitForPages waits until the Pages site has been built; pagesTimeout
+	// bounds the wait.
+	waitForPages bool
+	pagesTimeout time.Duration
+	// topicsFrom is a file with more topics, one per line.
+	topicsFrom string
+	// allowUpdateBranch always suggests updating pull request branches.
+	allowUpdateBranch bool
+	// secretScanning enables secret scanning; pushProtection also blocks
+	// pushes containing secrets and implies it.
+	secretScanning bool
+	pushProtection bool
+	// tagPushMode selects which local tags the push includes: none, follow
+	// or all.
+	tagPushMode string
+	// expandEmoji replaces :shortcode: emoji in the description with their
+	// Unicode characters.
+	expandEmoji bool
+	// force proceeds even when the current directory is inside another
+	// repository's work tree.
+	force bool
+	// makefile writes a Makefile for this ecosystem: go, node or generic.
+	makefile string
+	// noPush sets up the repository and the local commit but doesn't push.
+	noPush bool
+	// rename renames a reused repository to this name.
+	rename string
+	// assumeDefaultBranch is taken as the remote default branch instead of
+	// the one GitHub reports.
+	assumeDefaultBranch string
+	// autoLanguageTopics adds the top languages GitHub detects after the
+	// push as topics.
+	autoLanguageTopics bool
+	// splitCommit commits each top-level directory separately.
+	splitCommit bool
+	// hooks are the git hooks installed from -install-hook.
+	hooks []hookSpec
+	// orgRepoDefaults inherits repository defaults from the organization.
+	orgRepoDefaults bool
+	// given holds the names of the flags set on the command line or in git
+	// config, which organization defaults don't override.
+	given map[string]bool
+	// pushConcurrency is how many pushes -tag-push-mode all runs at once.
+	pushConcurrency int
+	// scanSecrets scans the staged changes for likely secrets before
+	// committing: block aborts on a finding, warn only reports it.
+	scanSecrets string
+	// secretRules is a JSON file overriding the rules of -scan-secrets;
+	// secretRuleSet holds the rules in effect.
+	secretRules   string
+	secretRuleSet *secretRules
+	// remoteHead makes this branch the default branch on GitHub.
+	remoteHead string
+	// initSubmodules checks out submodules before staging;
+	// recurseSubmodules pushes submodule commits their remotes lack.
+	initSubmodules    bool
+	recurseSubmodules bool
+	// orgSecrets are organization Actions secrets made available to the
+	// repository; orgSecretVisibility "selected" only accepts secrets
+	// shared with selected repositories.
+	orgSecrets          []string
+	orgSecretVisibility string
+	// promptDescription asks for a description on a terminal when none
+	// was given.
+	promptDescription bool
+	// licenseYear and licenseOwner fill in the LICENSE placeholders instead
+	// of the current year and the authenticated user's name.
+	licenseYear  string
+	licenseOwner string
+	// branch is the working branch, e.g. a ticket branch, that the commits go
+	// to instead of the default branch; keepDefaultBranch pushes the default
+	// branch too
+	branch            string
+	keepDefaultBranch bool
+	// cloneDepth limits the fetch of the branch GitHub created with -template
+	// or -auto-init to this many commits; 0 fetches all of them.
+	cloneDepth int
+}
+
+// Allowed values for the commit title/message flags, mapped to the
+// corresponding GitHub API enum values.
+var (
+	squashTitleValues = map[string]string{
+		"pr-title":     "PR_TITLE",
+		"commit-or-pr": "COMMIT_OR_PR_TITLE",
+	}
+	squashMessageValues = map[string]string{
+		"pr-body":         "PR_BODY",
+		"commit-messages": "COMMIT_MESSAGES",
+		"blank":           "BLANK",
+	}
+	mergeTitleValues = map[string]string{
+		"pr-title":      "PR_TITLE",
+		"merge-message": "MERGE_MESSAGE",
+	}
+	mergeMessageValues = map[string]string{
+		"pr-body":  "PR_BODY",
+		"pr-title": "PR_TITLE",
+		"blank":    "BLANK",
+	}
+)
+
+func parseFlags(args []string) (*options, error) {
+	opts := &options{}
+
+	fs := flag.NewFlagSet("repoinit", flag.ContinueOnError)
+	fs.BoolVar(&opts.reuse, "reuse", true, "use the existing repository if the name is already taken (set to false to fail instead)")
+	fs.StringVar(&opts.protectTags, "protect-tags", "", "protect tags matching `pattern` (e.g. 'v*') with a ruleset")
+	fs.BoolVar(&opts.autoMerge, "auto-merge", false, "allow auto-merge on pull requests")
+	fs.StringVar(&opts.squashTitle, "squash-title", "", "default squash merge commit title: pr-title|commit-or-pr")
+	fs.StringVar(&opts.squashMessage, "squash-message", "", "default squash merge commit message: pr-body|commit-messages|blank")
+	fs.StringVar(&opts.mergeTitle, "merge-title", "", "default merge commit title: pr-title|merge-message")
+	fs.StringVar(&opts.mergeMessage, "merge-message", "", "default merge commit message: pr-body|pr-title|blank")
+	fs.BoolVar(&opts.checkName, "check-name", false, "only check whether the repository name is available (exit 0 if free, 3 if taken)")
+	fs.DurationVar(&opts.deviceFlowTimeout, "device-flow-timeout", 0, "give up on device flow authorization after this long (default: GitHub's code expiry)")
+	fs.BoolVar(&opts.showTokenSource, "show-token-source", false, "log where the GitHub token was found (pass, 1password, env, config, gh, gh-stored or device-flow)")
+	fs.StringVar(&opts.org, "org", "", "create the repository under this `organization`")
+	fs.Func("gitignore-template", "comma-separated GitHub gitignore `templates` to merge into .gitignore (e.g. Go,macOS)", func(v string) error {
+		opts.gitignoreTemplates = append(opts.gitignoreTemplates, splitList(v)...)
+		return nil
+	})
+	fs.StringVar(&opts.license, "license", "", "add a LICENSE file for this SPDX `id` (e.g. mit, apache-2.0) to the initial commit")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "show what would be created and committed without changing anything")
+	fs.Var(optionalValue{&opts.envExample, ".env"}, "env-example", "commit a copy of the dotenv `file` with values blanked (default .env) and keep the real one ignored")
+	fs.BoolVar(&opts.noDefaultLabels, "no-default-labels", false, "delete the default labels GitHub adds to new repositories")
+	fs.StringVar(&opts.onSuccess, "on-success", "", "shell `command` to run after a successful push (REPOINIT_URL, REPOINIT_NAME, REPOINIT_OWNER and REPOINIT_SSH_URL are set)")
+	fs.Func("topics", "comma-separated `topics` to set on the repository", func(v string) error {
+		opts.topics = append(opts.topics, splitList(v)...)
+		return nil
+	})
+	fs.BoolVar(&opts.autoTopics, "auto-topics", false, "add topics for languages and tools detected in the working tree (go.mod, Dockerfile, ...)")
+	fs.StringVar(&opts.owner, "owner", "", "create the repository for this user or organization `name` (looked up automatically)")
+	fs.StringVar(&opts.color, "color", colorAuto, "colorize output: auto|always|never (auto disables color when piped, in CI or with NO_COLOR)")
+	fs.BoolFunc("no-color", "disable colored output (same as -color=never)", func(string) error {
+		opts.color = colorNever
+		return nil
+	})
+	fs.StringVar(&opts.tokenFromPass, "token-from-pass", "", "read the GitHub token from this pass `entry` (e.g. github/token)")
+	fs.StringVar(&opts.tokenFromOp, "token-from-op", "", "read the GitHub token from this 1Password secret `reference` (op://vault/item/field)")
+	fs.BoolVar(&opts.debugHTTP, "debug-http", false, "log HTTP requests and responses (credentials redacted) to stderr")
+	fs.BoolVar(&opts.syncExisting, "sync-existing", false, "when reusing a repository that already has commits, rebase local commits onto the remote branch before pushing")
+	fs.StringVar(&opts.teamMaintain, "team-maintain", "", "give this organization `team` maintain access and keep yourself admin")
+	fs.BoolVar(&opts.json, "json", false, "print the result as JSON on stdout (all other output goes to stderr)")
+	fs.BoolVar(&opts.renameExisting, "rename-existing", false, "rename the local branch to the default branch of an existing repository instead of failing on a mismatch")
+	fs.BoolVar(&opts.pages, "pages", false, "publish the pushed branch with GitHub Pages")
+	fs.BoolVar(&opts.homepageFromPages, "homepage-from-pages", false, "set the repository homepage to its GitHub Pages URL")
+	fs.BoolVar(&opts.autoInit, "auto-init", false, "let GitHub create the initial commit (README, and LICENSE with -license) and commit local files on top of it")
+	fs.BoolVar(&opts.refuseDirty, "refuse-dirty", false, "in a repository that already has commits, stop if there are uncommitted changes instead of committing them")
+	fs.StringVar(&opts.tagInitial, "tag-initial", "", "tag the initial commit with this `tag` (e.g. v0.0.0) and push it")
+	fs.BoolVar(&opts.tagAnnotated, "tag-annotated", false, "make the -tag-initial tag annotated")
+	fs.StringVar(&opts.tagMessage, "tag-message", "", "`message` for an annotated -tag-initial tag (implies -tag-annotated)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "print more details, such as the files staged for the initial commit")
+	fs.BoolVar(&opts.listFiles, "list-files", false, "list every staged file, even when there are too many for -verbose to show")
+	fs.StringVar(&opts.print, "print", "", "print only this `field` of the repository on stdout: ssh_url|clone_url|html_url|full_name (all other output goes to stderr)")
+	fs.StringVar(&opts.githubURL, "github-url", "", "`URL` of the GitHub instance, e.g. https://github.example.com (default: $GITHUB_SERVER_URL or https://github.com)")
+	fs.BoolVar(&opts.noInitialCommit, "no-initial-commit", false, "do not stage or commit anything; just create the repository, add the remote and push the current branch")
+	fs.BoolVar(&opts.topicsReplace, "topics-replace", false, "replace the topics of an existing repository instead of adding to them")
+	fs.StringVar(&opts.userAgent, "user-agent", "", "User-Agent `string` for GitHub requests (default: $REPOINIT_USER_AGENT or repoinit/<version>)")
+	fs.IntVar(&opts.maxRetries, "max-retries", 3, "retry failed GitHub requests and pushes up to `n` times (0 disables retries)")
+	fs.DurationVar(&opts.retryBaseDelay, "retry-base-delay", 500*time.Millisecond, "wait this long before the first retry, doubling with each further retry")
+	fs.BoolVar(&opts.insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "do not verify TLS certificates of the GitHub server (INSECURE, for test instances only)")
+	fs.StringVar(&opts.visibility, "visibility", "", "repository visibility: public|private|internal (default public, or internal with -org-create-as-internal-default)")
+	fs.BoolVar(&opts.orgInternalDefault, "org-create-as-internal-default", false, "create organization repositories as internal unless -visibility is given (also REPOINIT_ORG_CREATE_AS_INTERNAL_DEFAULT=true)")
+	fs.StringVar(&opts.commitMessage, "commit-message", "", "`message` for the initial commit (default \"Initial commit\")")
+	fs.StringVar(&opts.commitMessageFile, "commit-message-file", "", "read the initial commit message, subject and body, from this `file` (-commit-message wins if both are given)")
+	fs.StringVar(&opts.gitignoreMode, "gitignore-mode", gitignoreMerge, "what -gitignore-template does to an existing .gitignore: keep|merge|overwrite")
+	fs.BoolVar(&opts.printRateLimit, "print-rate-limit", false, "print the remaining GitHub API rate limit (core and GraphQL) after the run")
+	fs.StringVar(&opts.protectionFrom, "protection-from", "", "copy the default branch protection of `owner/repo` to the new repository as a ruleset")
+	fs.BoolVar(&opts.timings, "timings", false, "print how long each phase took (auth, create, git init, staging, commit, push, ...)")
+	fs.StringVar(&opts.manifest, "manifest", "", "stage only the paths listed in this `file` (one per line, # for comments), in the listed order")
+	fs.BoolVar(&opts.allowUnsigned, "allow-unsigned", false, "if signing the initial commit fails (commit.gpgsign without a usable key), commit without a signature")
+	fs.StringVar(&opts.since, "since", "", "publish only the history from this `commit` on, dropping everything before it (the local branch is not changed)")
+	fs.StringVar(&opts.description, "description", "", "repository `description`")
+	fs.BoolVar(&opts.noEditExisting, "no-edit-existing", false, "when reusing an existing repository, only add the remote and push; do not change its settings, topics or other configuration")
+	fs.Func("co-author", "add a Co-authored-by trailer for `\"Name <email>\"` to the initial commit (repeatable)", func(v string) error {
+		opts.coAuthors = append(opts.coAuthors, strings.TrimSpace(v))
+		return nil
+	})
+	fs.BoolVar(&opts.noVerifyPush, "no-verify-push", false, "do not check that the remote branch points at the pushed commit after pushing")
+	fs.StringVar(&opts.template, "template", "", "generate the repository from the template repository `owner/repo` and commit local files on top of its contents")
+	fs.Func("template-exclude", "with -template, remove template files matching this `glob` (matched against the path and the file name; repeatable)", func(v string) error {
+		opts.templateExcludes = append(opts.templateExcludes, v)
+		return nil
+	})
+	fs.BoolVar(&opts.changelog, "changelog", false, "write a Keep a Changelog style CHANGELOG.md with an Unreleased section, unless one exists")
+	fs.StringVar(&opts.changelogTemplate, "changelog-template", "", "write CHANGELOG.md from this `file` instead of the built-in template (implies -changelog)")
+	fs.Func("funding", "write .github/FUNDING.yml from comma-separated `platform:handle` pairs, e.g. github:me,custom:https://example.com/donate", func(v string) error {
+		entries, err := parseFunding(v)
+		if err != nil {
+			return err
+		}
+		opts.funding = append(opts.funding, entries...)
+		return nil
+	})
+	fs.Func("install-hook", "install the script at `path` as the git hook name, e.g. pre-push:scripts/pre-push.sh (repeatable)", func(v string) error {
+		hook, err := parseHook(v)
+		if err != nil {
+			return err
+		}
+		opts.hooks = append(opts.hooks, hook)
+		return nil
+	})
+	fs.StringVar(&opts.subtree, "subtree", "", "publish only the contents of this `directory`, as the root of the new repository, in a fresh single commit (the local repository and its origin are left alone)")
+	fs.Func("label", "create the label `name:color[:description]` (color as hex, e.g. bug:d73a4a:Something is broken; repeatable)", func(v string) error {
+		label, err := parseLabel(v)
+		if err != nil {
+			return err
+		}
+		opts.labels = append(opts.labels, label)
+		return nil
+	})
+	fs.StringVar(&opts.labelsFrom, "labels-from", "", "create the labels listed in this JSON `file` (an array of objects with name, color and description)")
+	fs.BoolVar(&opts.labelsSync, "labels-sync", false, "make the repository's labels exactly match -label and -labels-from: update differing labels and DELETE all others")
+	fs.BoolVar(&opts.noEnvFile, "no-env-file", false, "do not load environment variables (such as GITHUB_TOKEN) from .env in the current directory")
+	fs.StringVar(&opts.envFile, "env-file", "", "load environment variables from this dotenv `file` instead of .env in the current directory")
+	fs.StringVar(&opts.stageMode, "stage-mode", stageAll, "what to stage for the initial commit: `all` (everything that isn't hidden or ignored), tracked (only files git already tracks) or interactive (ask for each path)")
+	fs.StringVar(&opts.pagesDomain, "pages-domain", "", "with -pages, serve the site from this custom `domain` (writes CNAME and enforces HTTPS once the certificate is issued)")
+	fs.BoolVar(&opts.gitkeep, "gitkeep", false, "write a .gitkeep into every empty, non-ignored directory so the directory layout is committed")
+	fs.StringVar(&opts.defaultOwner, "default-owner", "", "write `@org/team` as the owner of every file into .github/CODEOWNERS (with -protect-default, their review is required)")
+	fs.BoolVar(&opts.protectDefault, "protect-default", false, "protect the pushed branch: changes need an approved pull request (and a code owner review with -default-owner)")
+	fs.BoolVar(&opts.waitForPages, "wait-for-pages", false, "with -pages, wait until the site has been built and print its live URL")
+	fs.DurationVar(&opts.pagesTimeout, "pages-timeout", 10*time.Minute, "give up waiting for the Pages build after this `duration` (with -wait-for-pages)")
+	fs.StringVar(&opts.topicsFrom, "topics-from", "", "add the topics listed in this `file` (one per line, # for comments)")
+	fs.BoolVar(&opts.allowUpdateBranch, "allow-update-branch", false, "always suggest updating pull request branches that are behind the base branch")
+	fs.BoolVar(&opts.secretScanning, "secret-scanning", false, "enable GitHub secret scanning")
+	fs.BoolVar(&opts.pushProtection, "push-protection", false, "enable secret scanning push protection, which blocks pushes containing secrets (implies -secret-scanning)")
+	fs.StringVar(&opts.tagPushMode, "tag-push-mode", tagPushNone, "which existing tags to push along with the branch: `none`, follow (annotated tags on the pushed commits, git push --follow-tags) or all (every tag, git push --tags)")
+	fs.BoolVar(&opts.expandEmoji, "expand-emoji", false, "replace :shortcode: emoji in -description with the emoji themselves, for tools that don't render shortcodes")
+	fs.BoolVar(&opts.force, "force", false, "proceed even though the current directory is a subdirectory of another git repository")
+	fs.StringVar(&opts.makefile, "makefile", "", "write a Makefile with build, test and lint targets for this `ecosystem` (go, node or generic), unless one exists")
+	fs.BoolVar(&opts.noPush, "no-push", false, "create and configure the repository and commit locally, but don't push (steps that need the pushed branch are skipped)")
+	fs.StringVar(&opts.rename, "rename", "", "when reusing an existing repository, rename it to `name` and point origin at the new name")
+	fs.StringVar(&opts.assumeDefaultBranch, "assume-default-branch", "", "take `branch` as the default branch of the repository instead of looking it up")
+	fs.BoolVar(&opts.autoLanguageTopics, "auto-language-topics", false, "after pushing, add the top languages GitHub detects in the repository as topics")
+	fs.BoolVar(&opts.splitCommit, "split-commit", false, "split the initial commit into one commit for the top-level files and one per top-level directory")
+	fs.BoolVar(&opts.orgRepoDefaults, "org-repo-defaults", false, "inherit the organization's defaults for new repositories (allowed visibility, secret scanning) where no flag says otherwise")
+	fs.IntVar(&opts.pushConcurrency, "push-concurrency", 1, "with -tag-push-mode all, push the tags in this `many` parallel pushes after the branch (at most 8)")
+	fs.Var(optionalValue{&opts.scanSecrets, scanSecretsBlock}, "scan-secrets", "scan the files being committed for likely secrets (token formats, high-entropy strings) and abort if any are found; `mode` warn only reports them")
+	fs.StringVar(&opts.secretRules, "secret-rules", "", "replace the -scan-secrets rules with those in this JSON `file`")
+	fs.StringVar(&opts.remoteHead, "remote-head", "", "after pushing, make `branch` the default branch on GitHub (and so origin/HEAD)")
+	fs.BoolVar(&opts.initSubmodules, "init-submodules", false, "run git submodule update --init --recursive before staging, so submodules can be referenced in the initial commit")
+	fs.BoolVar(&opts.recurseSubmodules, "recurse-submodules", false, "also push submodule commits their own remotes don't have yet (git push --recurse-submodules=on-demand)")
+	fs.Func("org-secret", "make the organization Actions secret `name` available to the repository (repeatable)", func(v string) error {
+		opts.orgSecrets = append(opts.orgSecrets, strings.TrimSpace(v))
+		return nil
+	})
+	fs.StringVar(&opts.orgSecretVisibility, "org-secret-visibility", "", "with `selected`, only accept -org-secret secrets shared with selected repositories, which the repository is added to")
+	fs.BoolVar(&opts.promptDescription, "prompt-description", true, "on a terminal, ask for an optional description if -description isn't given (set to false to never ask)")
+	fs.StringVar(&opts.licenseYear, "license-year", "", "copyright `year` (or range, e.g. 2019-2025) for -license instead of the current year")
+	fs.StringVar(&opts.licenseOwner, "license-owner", "", "copyright `holder` for -license instead of your GitHub name, e.g. \"Acme Inc\"")
+	fs.StringVar(&opts.branch, "branch", "", "commit to and push the working `branch` instead of the default one, e.g. JIRA-123-short-desc; the name is made git-legal")
+	fs.BoolVar(&opts.keepDefaultBranch, "keep-default-branch", false, "with -branch, also push the default branch at the same commit")
+	fs.IntVar(&opts.cloneDepth, "clone-depth", 0, "with -template or -auto-init, fetch only the last `n` commits of the branch GitHub created (0 fetches the whole history)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := applyGitConfigDefaults(fs); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	opts.given = map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { opts.given[f.Name] = true })
+	if err := validateOptions(opts); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	return opts, nil
+}
+
+// applyGitConfigDefaults sets the flags that weren't given on the command
+// line from the repoinit section of git config, so that e.g.
+// `git config --global repoinit.visibility private` changes the default of
+// -visibility. Keys are matched case-insensitively and with or without the
+// dashes of the flag name (repoinit.autoInit and repoinit.auto-init both
+// set -auto-init), and booleans are read the way git reads them. Repeated
+// keys set repeatable flags several times.
+func applyGitConfigDefaults(fs *flag.FlagSet) error {
+	out, err := gitOutput("config", "--get-regexp", `^repoinit\.`)
+	if err != nil || out == "" {
+		// git exits non-zero when nothing matches
+		return nil
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	names := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) { names[strings.ReplaceAll(f.Name, "-", "")] = f.Name })
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			value = "true"
+		}
+		name, known := names[strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(key, "repoinit.")), "-", "")]
+		if !known {
+			warnf("Ignoring git config %s: repoinit has no such option", key)
+			continue
+		}
+		if given[name] {
+			continue
+		}
+		if b, ok := fs.Lookup(name).Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			value = gitBool(value)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for git config %s: %v", value, key, err)
+		}
+	}
+	return nil
+}
+
+// gitBool translates git's spellings of booleans (yes/no, on/off, empty for
+// false) into ones strconv.ParseBool accepts.
+func gitBool(value string) string {
+	switch strings.ToLower(value) {
+	case "yes", "on":
+		return "true"
+	case "no", "off", "":
+		return "false"
+	}
+	return value
+}
+
+// validateOptions checks flag values that the flag package can't, and
+// normalizes them into the form used by the rest of the program.
+func validateOptions(opts *options) error {
+	var err error
+	if opts.squashTitle, err = enumFlag("squash-title", opts.squashTitle, squashTitleValues); err != nil {
+		return err
+	}
+	if opts.squashMessage, err = enumFlag("squash-message", opts.squashMessage, squashMessageValues); err != nil {
+		return err
+	}
+	if opts.mergeTitle, err = enumFlag("merge-title", opts.mergeTitle, mergeTitleValues); err != nil {
+		return err
+	}
+	if opts.mergeMessage, err = enumFlag("merge-message", opts.mergeMessage, mergeMessageValues); err != nil {
+		return err
+	}
+	for i, topic := range opts.topics {
+		if opts.topics[i], err = normalizeTopic(topic); err != nil {
+			return err
+		}
+	}
+	if opts.topicsFrom != "" {
+		topics, err := readTopics(opts.topicsFrom)
+		if err != nil {
+			return fmt.Errorf("invalid value for -topics-from: %w", err)
+		}
+		opts.topics = mergeTopics(opts.topics, topics)
+	}
+	if opts.owner != "" && opts.org != "" {
+		return errors.New("-owner and -org can't be used together")
+	}
+	switch opts.color {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return fmt.Errorf("invalid value %q for -color: must be one of auto, always, never", opts.color)
+	}
+	if opts.teamMaintain != "" && opts.org == "" && opts.owner == "" {
+		return errors.New("-team-maintain requires -org or an organization -owner")
+	}
+	if opts.tokenFromPass != "" && opts.tokenFromOp != "" {
+		return errors.New("-token-from-pass and -token-from-op can't be used together")
+	}
+	if opts.tagInitial != "" && !validTagName(opts.tagInitial) {
+		return fmt.Errorf("invalid value %q for -tag-initial: not a valid git tag name", opts.tagInitial)
+	}
+	if (opts.tagAnnotated || opts.tagMessage != "") && opts.tagInitial == "" {
+		return errors.New("-tag-annotated and -tag-message require -tag-initial")
+	}
+	if opts.print != "" {
+		if _, ok := printFields[opts.print]; !ok {
+			return fmt.Errorf("invalid value %q for -print: must be one of clone_url, full_name, html_url, ssh_url", opts.print)
+		}
+		if opts.json {
+			return errors.New("-print and -json can't be used together")
+		}
+	}
+	if opts.changelogTemplate != "" {
+		if _, err := os.Stat(opts.changelogTemplate); err != nil {
+			return fmt.Errorf("invalid value %q for -changelog-template: %w", opts.changelogTemplate, err)
+		}
+		opts.changelog = true
+	}
+	if opts.noInitialCommit && (opts.license != "" || len(opts.gitignoreTemplates) > 0 || opts.envExample != "" || opts.autoInit || opts.changelog || len(opts.funding) > 0 || opts.makefile != "") {
+		return errors.New("-no-initial-commit can't be combined with -license, -gitignore-template, -env-example, -changelog, -funding, -makefile or -auto-init, which add files to the initial commit")
+	}
+	if opts.maxRetries < 0 || opts.maxRetries > maxRetries {
+		return fmt.Errorf("invalid value %d for -max-retries: must be between 0 and %d", opts.maxRetries, maxRetries)
+	}
+	if opts.retryBaseDelay <= 0 || opts.retryBaseDelay > maxRetryDelay {
+		return fmt.Errorf("invalid value %s for -retry-base-delay: must be positive and at most %s", opts.retryBaseDelay, maxRetryDelay)
+	}
+	switch opts.visibility {
+	case "", visibilityPublic, visibilityPrivate, visibilityInternal:
+	default:
+		return fmt.Errorf("invalid value %q for -visibility: must be one of public, private, internal", opts.visibility)
+	}
+	// The inline message wins, so the file only has to be valid when used
+	if opts.commitMessageFile != "" && opts.commitMessage == "" {
+		data, err := os.ReadFile(opts.commitMessageFile)
+		if err != nil {
+			return fmt.Errorf("invalid value for -commit-message-file: %w", err)
+		}
+		if opts.commitMessage = strings.TrimSpace(string(data)); opts.commitMessage == "" {
+			return fmt.Errorf("invalid value for -commit-message-file: %s is empty", opts.commitMessageFile)
+		}
+	}
+	switch opts.gitignoreMode {
+	case gitignoreKeep, gitignoreMerge, gitignoreOverwrite:
+	default:
+		return fmt.Errorf("invalid value %q for -gitignore-mode: must be one of keep, merge, overwrite", opts.gitignoreMode)
+	}
+	if opts.protectionFrom != "" {
+		if owner, repo, ok := strings.Cut(opts.protectionFrom, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return fmt.Errorf("invalid value %q for -protection-from: must be owner/repo", opts.protectionFrom)
+		}
+	}
+	if opts.manifest != "" {
+		if opts.manifestPaths, err = readManifest(opts.manifest); err != nil {
+			return fmt.Errorf("invalid value for -manifest: %w", err)
+		}
+	}
+	if opts.since != "" {
+		if _, err := gitOutput("rev-parse", "--verify", "--quiet", opts.since+"^{commit}"); err != nil {
+			return fmt.Errorf("invalid value %q for -since: no such commit", opts.since)
+		}
+		if opts.tagInitial != "" || opts.syncExisting || opts.autoInit {
+			return errors.New("-since can't be combined with -tag-initial, -sync-existing or -auto-init")
+		}
+	}
+	for _, author := range opts.coAuthors {
+		if !coAuthorPattern.MatchString(author) {
+			return fmt.Errorf("invalid value %q for -co-author: must be \"Name <email>\"", author)
+		}
+	}
+	if opts.template != "" {
+		if owner, repo, ok := strings.Cut(opts.template, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return fmt.Errorf("invalid value %q for -template: must be owner/repo", opts.template)
+		}
+		if opts.autoInit || opts.noInitialCommit {
+			return errors.New("-template can't be combined with -auto-init or -no-initial-commit")
+		}
+	}
+	if len(opts.templateExcludes) > 0 && opts.template == "" {
+		return errors.New("-template-exclude requires -template")
+	}
+	for _, pattern := range opts.templateExcludes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid value %q for -template-exclude: %v", pattern, err)
+		}
+	}
+	if opts.subtree != "" {
+		if fi, err := os.Stat(opts.subtree); err != nil || !fi.IsDir() {
+			return fmt.Errorf("invalid value %q for -subtree: not a directory", opts.subtree)
+		}
+		if dir := filepath.Clean(opts.subtree); dir == "." || filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid value %q for -subtree: must be a subdirectory of the current directory", opts.subtree)
+		}
+		if opts.license != "" || len(opts.gitignoreTemplates) > 0 || opts.envExample != "" || opts.changelog || len(opts.funding) > 0 || opts.makefile != "" ||
+			opts.autoInit || opts.template != "" || opts.manifest != "" || opts.since != "" || opts.syncExisting || opts.tagInitial != "" || opts.noInitialCommit {
+			return errors.New("-subtree can't be combined with -license, -gitignore-template, -env-example, -changelog, -funding, -makefile, -auto-init, -template, -manifest, -since, -sync-existing, -tag-initial or -no-initial-commit")
+		}
+	}
+	if opts.labelsFrom != "" {
+		labels, err := readLabels(opts.labelsFrom)
+		if err != nil {
+			return fmt.Errorf("invalid value for -labels-from: %w", err)
+		}
+		opts.labels = append(opts.labels, labels...)
+	}
+	if opts.labelsSync && len(opts.labels) == 0 {
+		return errors.New("-labels-sync requires -label or -labels-from")
+	}
+	if opts.envFile != "" {
+		if opts.noEnvFile {
+			return errors.New("-env-file and -no-env-file can't be used together")
+		}
+		if _, err := os.Stat(opts.envFile); err != nil {
+			return fmt.Errorf("invalid value %q for -env-file: %w", opts.envFile, err)
+		}
+	}
+	switch opts.stageMode {
+	case stageAll:
+	case stageTracked, stageInteractive:
+		if opts.manifest != "" || opts.subtree != "" || opts.noInitialCommit {
+			return fmt.Errorf("-stage-mode %s can't be combined with -manifest, -subtree or -no-initial-commit", opts.stageMode)
+		}
+		if opts.stageMode == stageTracked && (opts.autoInit || opts.template != "") {
+			return errors.New("-stage-mode tracked can't be combined with -auto-init or -template")
+		}
+		if opts.stageMode == stageInteractive && !isInteractive() {
+			return errors.New("-stage-mode interactive needs a terminal")
+		}
+	default:
+		return fmt.Errorf("invalid value %q for -stage-mode: must be one of all, tracked, interactive", opts.stageMode)
+	}
+	if opts.pagesDomain != "" {
+		if !opts.pages {
+			return errors.New("-pages-domain requires -pages")
+		}
+		opts.pagesDomain = strings.ToLower(strings.TrimSuffix(opts.pagesDomain, "."))
+		if !domainPattern.MatchString(opts.pagesDomain) {
+			return fmt.Errorf("invalid value %q for -pages-domain: must be a domain name such as docs.example.com", opts.pagesDomain)
+		}
+	}
+	if opts.defaultOwner != "" {
+		if !teamHandlePattern.MatchString(opts.defaultOwner) {
+			return fmt.Errorf("invalid value %q for -default-owner: must be a team handle such as @org/team", opts.defaultOwner)
+		}
+		if opts.noInitialCommit || opts.subtree != "" {
+			return errors.New("-default-owner can't be combined with -no-initial-commit or -subtree")
+		}
+	}
+	if opts.waitForPages && !opts.pages {
+		return errors.New("-wait-for-pages requires -pages")
+	}
+	if opts.pagesTimeout <= 0 {
+		return fmt.Errorf("invalid value %s for -pages-timeout: must be positive", opts.pagesTimeout)
+	}
+	if opts.pushProtection {
+		opts.secretScanning = true
+	}
+	switch opts.tagPushMode {
+	case tagPushNone:
+	case tagPushFollow, tagPushAll:
+		if opts.since != "" || opts.subtree != "" {
+			return fmt.Errorf("-tag-push-mode %s can't be combined with -since or -subtree, which don't publish the local history", opts.tagPushMode)
+		}
+	default:
+		return fmt.Errorf("invalid value %q for -tag-push-mode: must be one of none, follow, all", opts.tagPushMode)
+	}
+	if opts.expandEmoji {
+		opts.description = expandEmoji(opts.description)
+	}
+	if opts.makefile != "" && !makefileKinds[opts.makefile] {
+		return fmt.Errorf("invalid value %q for -makefile: must be one of go, node, generic", opts.makefile)
+	}
+	if opts.rename != "" {
+		if strings.ContainsAny(opts.rename, "/ ") {
+			return fmt.Errorf("invalid value %q for -rename: must be a repository name without owner", opts.rename)
+		}
+		if !opts.reuse {
+			return errors.New("-rename can't be combined with -reuse=false")
+		}
+		if opts.noEditExisting {
+			return errors.New("-rename can't be combined with -no-edit-existing")
+		}
+	}
+	if opts.assumeDefaultBranch != "" && !validBranchName(opts.assumeDefaultBranch) {
+		return fmt.Errorf("invalid value %q for -assume-default-branch: not a valid branch name", opts.assumeDefaultBranch)
+	}
+	if opts.splitCommit && (opts.noInitialCommit || opts.subtree != "") {
+		return errors.New("-split-commit can't be combined with -no-initial-commit or -subtree, which don't create the initial commit")
+	}
+	for _, h := range opts.hooks {
+		if fi, err := os.Stat(h.script); err != nil || fi.IsDir() {
+			return fmt.Errorf("invalid value %q for -install-hook: %s is not a file", h.name+":"+h.script, h.script)
+		}
+	}
+	if opts.pushConcurrency < 1 || opts.pushConcurrency > maxPushConcurrency {
+		return fmt.Errorf("invalid value %d for -push-concurrency: must be between 1 and %d", opts.pushConcurrency, maxPushConcurrency)
+	}
+	if opts.pushConcurrency > 1 && opts.tagPushMode != tagPushAll {
+		return errors.New("-push-concurrency needs -tag-push-mode all; a single branch is pushed in one go")
+	}
+	switch opts.scanSecrets {
+	case "":
+		if opts.secretRules != "" {
+			return errors.New("-secret-rules needs -scan-secrets")
+		}
+	case scanSecretsBlock, scanSecretsWarn:
+		rules, err := readSecretRules(opts.secretRules)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for -secret-rules: %w", opts.secretRules, err)
+		}
+		opts.secretRuleSet = rules
+	default:
+		return fmt.Errorf("invalid value %q for -scan-secrets: must be block or warn", opts.scanSecrets)
+	}
+	if opts.remoteHead != "" && !validBranchName(opts.remoteHead) {
+		return fmt.Errorf("invalid value %q for -remote-head: not a valid branch name", opts.remoteHead)
+	}
+	if (opts.initSubmodules || opts.recurseSubmodules) && opts.subtree != "" {
+		return errors.New("-init-submodules and -recurse-submodules can't be combined with -subtree")
+	}
+	if opts.orgSecretVisibility != "" && opts.orgSecretVisibility != orgSecretVisibilitySelected {
+		return fmt.Errorf("invalid value %q for -org-secret-visibility: must be selected", opts.orgSecretVisibility)
+	}
+	if opts.orgSecretVisibility != "" && len(opts.orgSecrets) == 0 {
+		return errors.New("-org-secret-visibility needs -org-secret")
+	}
+	if (opts.licenseYear != "" || opts.licenseOwner != "") && (opts.license == "" || opts.autoInit) {
+		return errors.New("-license-year and -license-owner need -license and can't be combined with -auto-init, where GitHub writes LICENSE")
+	}
+	if opts.licenseYear != "" && !licenseYearPattern.MatchString(opts.licenseYear) {
+		return fmt.Errorf("invalid value %q for -license-year: must be a year such as 2025 or a range such as 2019-2025", opts.licenseYear)
+	}
+	if opts.branch != "" {
+		branch := sanitizeBranchName(opts.branch)
+		if branch == "" || !validBranchName(branch) {
+			return fmt.Errorf("invalid value %q for -branch: not a valid branch name", opts.branch)
+		}
+		opts.branch = branch
+	}
+	if opts.keepDefaultBranch && opts.branch == "" {
+		return errors.New("-keep-default-branch requires -branch")
+	}
+	if opts.branch != "" && (opts.subtree != "" || opts.since != "") {
+		return errors.New("-branch can't be combined with -subtree or -since")
+	}
+	if opts.cloneDepth < 0 {
+		return fmt.Errorf("invalid value %d for -clone-depth: must not be negative", opts.cloneDepth)
+	}
+	if opts.cloneDepth > 0 && opts.template == "" && !opts.autoInit {
+		return errors.New("-clone-depth requires -template or -auto-init")
+	}
+	if opts.deviceFlowTimeout < 0 {
+		return fmt.Errorf("invalid value %s for -device-flow-timeout: must not be negative", opts.deviceFlowTimeout)
+	}
+	return nil
+}
+
+// enumFlag maps value to its entry in allowed. An empty value is left unset.
+func enumFlag(name, value string, allowed map[string]string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if mapped, ok := allowed[strings.ToLower(value)]; ok {
+		return mapped, nil
+	}
+	keys := make([]string, 0, len(allowed))
+	for k := range allowed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return "", fmt.Errorf("invalid value %q for -%s: must be one of %s", value, name, strings.Join(keys, ", "))
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// optionalValue is a string flag whose value may be omitted: "-flag" sets it
+// to def, "-flag=value" to value. Because the flag package treats it like a
+// boolean, a value must be attached with "=".
+type optionalValue struct {
+	target *string
+	def    string
+}
+
+func (o optionalValue) String() string {
+	if o.target == nil {
+		return ""
+	}
+	return *o.target
+}
+
+func (o optionalValue) Set(v string) error {
+	if v == "true" {
+		v = o.def
+	} else if v == "false" {
+		v = ""
+	}
+	*o.target = v
+	return nil
+}
+
+func (o optionalValue) IsBoolFlag() bool { return true }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownFundingPlatforms are the FUNDING.yml keys -funding accepts without a
+// warning.
+var knownFundingPlatforms = map[string]bool{
+	"github":          true,
+	"patreon":         true,
+	"open_collective": true,
+	"ko_fi":           true,
+	"custom":          true,
+}
+
+// fundingEntry is one platform:handle pair of -funding.
+type fundingEntry struct {
+	platform string
+	handle   string
+}
+
+// parseFunding parses comma-separated platform:handle pairs. Only the first
+// colon separates, so custom URLs can be given as is.
+func parseFunding(value string) ([]fundingEntry, error) {
+	var entries []fundingEntry
+	for _, pair := range strings.Split(value, ",") {
+		platform, handle, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		platform, handle = strings.TrimSpace(platform), strings.TrimSpace(handle)
+		if !ok || platform == "" || handle == "" {
+			return nil, fmt.Errorf("%q is not a platform:handle pair", pair)
+		}
+		entries = append(entries, fundingEntry{platform: strings.ToLower(platform), handle: handle})
+	}
+	return entries, nil
+}
+
+// writeFunding writes entries as a FUNDING.yml to path, one key per platform
+// in the order first given. Platforms with several handles (e.g. several
+// GitHub Sponsors accounts) become lists. Unknown platforms are written but
+// warned about, since GitHub ignores keys it doesn't know.
+func writeFunding(path string, entries []fundingEntry) error {
+	var platforms []string
+	handles := map[string][]string{}
+	for _, e := range entries {
+		if _, seen := handles[e.platform]; !seen {
+			platforms = append(platforms, e.platform)
+			if !knownFundingPlatforms[e.platform] {
+				warnf("Unknown funding platform %q; GitHub may ignore it (known: github, patreon, open_collective, ko_fi, custom)", e.platform)
+			}
+		}
+		handles[e.platform] = append(handles[e.platform], e.handle)
+	}
+
+	var b strings.Builder
+	for _, platform := range platforms {
+		values := handles[platform]
+		for i, v := range values {
+			// URLs are quoted so YAML doesn't trip over their characters
+			if platform == "custom" {
+				values[i] = fmt.Sprintf("%q", v)
+			}
+		}
+		if len(values) == 1 {
+			fmt.Fprintf(&b, "%s: %s\n", platform, values[0])
+		} else {
+			fmt.Fprintf(&b, "%s: [%s]\n", platform, strings.Join(values, ", "))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeScaffoldFile(path, b.String())
+}
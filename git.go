@@ -0,0 +1,512 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gitConfigOverrides are passed to every git invocation so that interactive
+// conveniences from the user's config (autocorrect prompts and delays, advice
+// hints) can't interfere with the commands repoinit drives.
+var gitConfigOverrides = []string{
+	"-c", "help.autocorrect=0",
+	"-c", "advice.defaultBranchName=false",
+	"-c", "advice.detachedHead=false",
+	"-c", "advice.addIgnoredFile=false",
+	"-c", "advice.addEmbeddedRepo=false",
+}
+
+// gitArgs prefixes args with gitConfigOverrides.
+func gitArgs(args ...string) []string {
+	return append(append([]string{}, gitConfigOverrides...), args...)
+}
+
+// gitCommand returns an unstarted git command for args.
+func gitCommand(args ...string) *exec.Cmd {
+	return exec.Command("git", gitArgs(args...)...)
+}
+
+// runGit runs git with its output streamed to the terminal.
+func runGit(args ...string) error {
+	return execCmd("git", gitArgs(args...)...)
+}
+
+// gitOutput runs git and returns its trimmed standard output.
+func gitOutput(args ...string) (string, error) {
+	out, err := gitCommand(args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// setRemote points the remote name at url: with set-url if it already exists,
+// otherwise by adding it.
+func setRemote(name, url string) error {
+	out, err := gitOutput("remote")
+	if err != nil {
+		return fmt.Errorf("failed to list remotes: %w", err)
+	}
+	for _, remote := range strings.Fields(out) {
+		if remote == name {
+			return runGit("remote", "set-url", name, url)
+		}
+	}
+	return runGit("remote", "add", name, url)
+}
+
+// originRepo returns the owner and name of the GitHub repository the
+// current repository's origin points at. Remotes on another host, and an
+// origin inherited from a repository in some parent directory, don't count.
+func originRepo() (owner, name string, ok bool) {
+	if !isInitialized() {
+		return "", "", false
+	}
+	remote, err := gitOutput("remote", "get-url", "origin")
+	if err != nil {
+		return "", "", false
+	}
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		// https://host/owner/name.git, ssh://git@host/owner/name.git
+		host, path = u.Hostname(), u.Path
+	} else if before, after, found := strings.Cut(remote, ":"); found && !strings.Contains(before, "/") {
+		// git@host:owner/name.git
+		_, host, _ = strings.Cut(before, "@")
+		if host == "" {
+			host = before
+		}
+		path = after
+	}
+	if !strings.EqualFold(host, githubHost()) {
+		return "", "", false
+	}
+	owner, name, found := strings.Cut(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
+
+// remoteBranchExists reports whether origin/branch is known locally (i.e.
+// after a fetch).
+func remoteBranchExists(branch string) bool {
+	_, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch)
+	return err == nil
+}
+
+// syncWithRemote fetches origin and rebases the local commits of branch onto
+// origin/branch, so that pushing fast-forwards the remote. If the remote
+// doesn't have the branch there's nothing to sync. On conflicts the rebase is
+// aborted, leaving the local branch as it was.
+func syncWithRemote(branch string) error {
+	if err := runGit("fetch", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch origin: %w", err)
+	}
+	if !remoteBranchExists(branch) {
+		return nil
+	}
+	if err := runGit("rebase", "origin/"+branch); err != nil {
+		if abortErr := gitCommand("rebase", "--abort").Run(); abortErr != nil {
+			return fmt.Errorf("rebasing onto origin/%s failed and could not be aborted (%v); finish or abort it with `git rebase --continue` or `git rebase --abort`", branch, abortErr)
+		}
+		return fmt.Errorf("your changes conflict with origin/%s, so the rebase was aborted and %s is unchanged. To publish them, run `git pull --rebase origin %s`, resolve the conflicts, and push", branch, branch, branch)
+	}
+	return nil
+}
+
+// isInitialized reports whether the current directory has a git repository
+// of its own. git decides rather than a check for a .git directory, so a
+// linked worktree (whose .git is a file) and an explicit GIT_DIR count too.
+// A repository in some parent directory doesn't.
+func isInitialized() bool {
+	if _, err := gitOutput("rev-parse", "--git-dir"); err != nil {
+		return false
+	}
+	if os.Getenv("GIT_DIR") != "" {
+		return true
+	}
+	top, err := enclosingWorktree()
+	return err == nil && top == ""
+}
+
+// invalidBranchChars are characters git doesn't allow in branch names, and
+// whitespace.
+var invalidBranchChars = regexp.MustCompile(`[\x00-\x20\x7f~^:?*\[\\]+`)
+
+// sanitizeBranchName makes name, e.g. a ticket title, into a legal branch
+// name: runs of disallowed characters become a dash, and the sequences and
+// ends git rejects are removed.
+func sanitizeBranchName(name string) string {
+	name = invalidBranchChars.ReplaceAllString(name, "-")
+	name = strings.ReplaceAll(name, "@{", "-")
+	for strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	for strings.Contains(name, "//") {
+		name = strings.ReplaceAll(name, "//", "/")
+	}
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	// No component may start with a dot or end in .lock
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = strings.TrimSuffix(strings.TrimLeft(part, "."), ".lock")
+	}
+	name = strings.Join(slices.DeleteFunc(parts, func(p string) bool { return p == "" }), "/")
+	return strings.Trim(name, "-.")
+}
+
+// hasCommits reports whether HEAD points at a commit, i.e. the current branch
+// isn't unborn.
+func hasCommits() bool {
+	_, err := gitOutput("rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+// adoptRemoteBranch makes origin/branch the base of a repository without
+// commits: HEAD is pointed at branch and reset to the remote commit while the
+// working tree is left alone, so the local files can be committed on top.
+// A depth above 0 fetches only that many commits, leaving a shallow
+// repository.
+func adoptRemoteBranch(branch string, depth int) error {
+	args := []string{"fetch", "origin"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	if err := runGit(args...); err != nil {
+		return fmt.Errorf("failed to fetch origin: %w", err)
+	}
+	if !remoteBranchExists(branch) {
+		return fmt.Errorf("origin has no branch %s", branch)
+	}
+	if err := runGit("symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("failed to switch to %s: %w", branch, err)
+	}
+	if err := runGit("reset", "-q", "origin/"+branch); err != nil {
+		return fmt.Errorf("failed to reset to origin/%s: %w", branch, err)
+	}
+	return nil
+}
+
+// restoreMissingFiles checks out the files that are tracked but missing from
+// the working tree, such as those of a branch adopted by adoptRemoteBranch.
+// Files that exist locally are left alone.
+func restoreMissingFiles() error {
+	out, err := gitOutput("ls-files", "--deleted")
+	if err != nil || out == "" {
+		return err
+	}
+	return runGit(append([]string{"checkout", "--"}, strings.Split(out, "\n")...)...)
+}
+
+// hasStagedChanges reports whether the index differs from HEAD.
+func hasStagedChanges() bool {
+	return gitCommand("diff", "--cached", "--quiet").Run() != nil
+}
+
+// uncommittedChanges returns git's short status of modified and untracked
+// files, or an empty string for a clean working tree.
+func uncommittedChanges() (string, error) {
+	// Not gitOutput: leading spaces are part of the status columns
+	out, err := gitCommand("status", "--porcelain").Output()
+	return strings.TrimRight(string(out), "\n"), err
+}
+
+// validTagName reports whether name is a tag name git accepts.
+func validTagName(name string) bool {
+	return gitCommand("check-ref-format", "refs/tags/"+name).Run() == nil
+}
+
+// validBranchName reports whether name is a branch name git accepts.
+func validBranchName(name string) bool {
+	return gitCommand("check-ref-format", "--branch", name).Run() == nil
+}
+
+// createTag tags HEAD with name. A non-empty message makes it an annotated
+// tag, otherwise it's lightweight.
+func createTag(name, message string) error {
+	if message != "" {
+		return runGit("tag", "-a", name, "-m", message)
+	}
+	return runGit("tag", name)
+}
+
+// Values of -tag-push-mode, and the git push option for each.
+const (
+	tagPushNone   = "none"
+	tagPushFollow = "follow"
+	tagPushAll    = "all"
+)
+
+var tagPushArgs = map[string][]string{
+	tagPushFollow: {"--follow-tags"},
+	tagPushAll:    {"--tags"},
+}
+
+// pushBatchSize is how many refs each push of pushConcurrently sends.
+const pushBatchSize = 100
+
+// maxPushConcurrency caps -push-concurrency; GitHub throttles clients that
+// open many pushes at once.
+const maxPushConcurrency = 8
+
+// pushConcurrently pushes refs to remote in batches, running up to
+// concurrency pushes at once. Each batch is retried on its own; the errors
+// of all failed batches are returned together.
+func pushConcurrently(remote string, refs []string, concurrency int) error {
+	batches := make(chan []string)
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for range min(concurrency, maxPushConcurrency) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				push := func() error { return runGit(append([]string{"push", "--quiet", remote}, batch...)...) }
+				if err := retries.do(fmt.Sprintf("Push of %d ref(s)", len(batch)), push); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s..%s: %w", batch[0], batch[len(batch)-1], err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for start := 0; start < len(refs); start += pushBatchSize {
+		batches <- refs[start:min(start+pushBatchSize, len(refs))]
+	}
+	close(batches)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// updateRemoteHead points refs/remotes/<remote>/HEAD at the remote's default
+// branch, as git clone would, and returns the branch it points at.
+func updateRemoteHead(remote string) (string, error) {
+	if _, err := gitOutput("remote", "set-head", remote, "--auto"); err != nil {
+		return "", err
+	}
+	head, err := gitOutput("symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(head, remote+"/"), nil
+}
+
+// localTags returns the refs of all local tags.
+func localTags() ([]string, error) {
+	out, err := gitOutput("for-each-ref", "--format=%(refname)", "refs/tags")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// unpushedCommits counts the commits on HEAD that haven't been pushed: those
+// missing from its upstream or, without one, from every remote-tracking
+// branch.
+func unpushedCommits() (int, error) {
+	revs := []string{"HEAD", "--not", "--remotes"}
+	if _, err := gitOutput("rev-parse", "--abbrev-ref", "@{u}"); err == nil {
+		revs = []string{"@{u}..HEAD"}
+	}
+	out, err := gitOutput(append([]string{"rev-list", "--count"}, revs...)...)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// errSigningFailed is returned by commit when git couldn't sign the commit,
+// typically because commit.gpgsign is inherited from a global config on a
+// machine that doesn't have the signing key.
+var errSigningFailed = errors.New("commit signing failed")
+
+// signingFailureMarkers are fragments of git's messages for GPG, SSH and
+// X.509 signing failures.
+var signingFailureMarkers = []string{
+	"gpg failed to sign",
+	"cannot run gpg",
+	"failed to sign",
+	"signing failed",
+	"couldn't load public key",
+}
+
+// commit commits the index with message. Signing failures are reported as
+// errSigningFailed.
+func commit(message string, extraArgs ...string) error {
+	return commitPaths(message, nil, extraArgs...)
+}
+
+// commitPaths commits like commit, but only paths if there are any, leaving
+// the rest of the index staged.
+func commitPaths(message string, paths []string, extraArgs ...string) error {
+	var stderr strings.Builder
+	args := append(extraArgs, "commit", "-m", message)
+	if len(paths) > 0 {
+		args = append(append([]string{"--literal-pathspecs"}, args...), "--pathspec-from-file=-", "--pathspec-file-nul")
+	}
+	cmd := gitCommand(args...)
+	if len(paths) > 0 {
+		cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00"))
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		out := strings.ToLower(stderr.String())
+		for _, marker := range signingFailureMarkers {
+			if strings.Contains(out, marker) {
+				return fmt.Errorf("%w: %v", errSigningFailed, err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// commitUnsigned commits like commitPaths but with signing turned off.
+func commitUnsigned(message string, paths []string) error {
+	return commitPaths(message, paths, "-c", "commit.gpgsign=false")
+}
+
+// signingFailureHelp explains a signing failure in terms of the user's git
+// config.
+func signingFailureHelp() string {
+	key, _ := gitOutput("config", "user.signingkey")
+	format, _ := gitOutput("config", "gpg.format")
+	if format == "" {
+		format = "openpgp"
+	}
+	if key == "" {
+		key = "(not set; git uses the key matching your committer email)"
+	}
+	return fmt.Sprintf("git couldn't sign the commit: commit.gpgsign is enabled, but the %s signing key %s is missing, locked or its agent isn't running. Make the key available (e.g. import it or unlock it with your agent), disable signing for this repository with `git config commit.gpgsign false`, or rerun with -allow-unsigned", format, key)
+}
+
+// commitSince recreates the history from ref to HEAD without anything before
+// ref and returns the new tip. ref becomes a root commit with the same tree,
+// message and authorship, and every later commit on the first-parent line is
+// replayed on top of it, merges flattened. Nothing in the repository changes
+// besides the new commit objects, so the local branch keeps its full history.
+func commitSince(ref string) (string, error) {
+	if err := gitCommand("merge-base", "--is-ancestor", ref, "HEAD").Run(); err != nil {
+		return "", fmt.Errorf("%s is not an ancestor of HEAD", ref)
+	}
+	revs, err := gitOutput("rev-list", "--reverse", "--first-parent", ref+"..HEAD")
+	if err != nil {
+		return "", err
+	}
+	base, err := gitOutput("rev-parse", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	commits := []string{base}
+	if revs != "" {
+		commits = append(commits, strings.Split(revs, "\n")...)
+	}
+
+	parent := ""
+	for _, c := range commits {
+		if parent, err = recommit(c, parent); err != nil {
+			return "", fmt.Errorf("failed to rewrite %s: %w", c, err)
+		}
+	}
+	return parent, nil
+}
+
+// recommit creates a copy of commit with parent as its only parent (or none
+// if parent is empty) and returns its id.
+func recommit(commit, parent string) (string, error) {
+	info, err := gitOutput("log", "-1", "--date=raw", "--format=%T%x00%an%x00%ae%x00%ad%x00%cn%x00%ce%x00%cd", commit)
+	if err != nil {
+		return "", err
+	}
+	f := strings.Split(info, "\x00")
+	if len(f) != 7 {
+		return "", fmt.Errorf("unexpected commit info %q", info)
+	}
+	message, err := gitCommand("log", "-1", "--format=%B", commit).Output()
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"commit-tree", f[0]}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	cmd := gitCommand(append(args, "-F", "-")...)
+	cmd.Stdin = strings.NewReader(string(message))
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+f[1], "GIT_AUTHOR_EMAIL="+f[2], "GIT_AUTHOR_DATE="+f[3],
+		"GIT_COMMITTER_NAME="+f[4], "GIT_COMMITTER_EMAIL="+f[5], "GIT_COMMITTER_DATE="+f[6],
+	)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// remoteBranchHead returns the commit remote (a name or URL) reports for
+// branch, asking the remote itself rather than trusting the local tracking
+// branch.
+func remoteBranchHead(remote, branch string) (string, error) {
+	out, err := gitOutput("ls-remote", remote, "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	sha, _, _ := strings.Cut(out, "\t")
+	if sha == "" {
+		return "", fmt.Errorf("%s has no branch %s", remote, branch)
+	}
+	return sha, nil
+}
+
+// commitSubtree creates a root commit with message whose tree is the current
+// content of dir, so dir/foo.go becomes foo.go, and returns its id. Files are
+// staged into a temporary index (ignore rules apply as usual), so the
+// repository's own index and branches are left alone.
+func commitSubtree(dir, message string) (string, error) {
+	tmp, err := os.MkdirTemp("", "repoinit-subtree")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(tmp, "index"))
+
+	// write-tree wants the prefix relative to the top of the work tree
+	prefix, err := gitOutput("rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	prefix += filepath.ToSlash(filepath.Clean(dir)) + "/"
+
+	add := gitCommand("add", "-A", "--", dir)
+	add.Env, add.Stderr = env, os.Stderr
+	if err := add.Run(); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", dir, err)
+	}
+	writeTree := gitCommand("write-tree", "--prefix="+prefix)
+	writeTree.Env = env
+	tree, err := writeTree.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree of %s: %w", dir, err)
+	}
+
+	cmd := gitCommand("commit-tree", strings.TrimSpace(string(tree)), "-F", "-")
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownHooks are the client-side hooks git runs, as listed in githooks(5).
+var knownHooks = map[string]bool{
+	"applypatch-msg":        true,
+	"pre-applypatch":        true,
+	"post-applypatch":       true,
+	"pre-commit":            true,
+	"pre-merge-commit":      true,
+	"prepare-commit-msg":    true,
+	"commit-msg":            true,
+	"post-commit":           true,
+	"pre-rebase":            true,
+	"post-checkout":         true,
+	"post-merge":            true,
+	"pre-push":              true,
+	"post-rewrite":          true,
+	"sendemail-validate":    true,
+	"fsmonitor-watchman":    true,
+	"post-index-change":     true,
+	"reference-transaction": true,
+	"push-to-checkout":      true,
+}
+
+// hookSpec is a hook to install, as given by -install-hook.
+type hookSpec struct {
+	name   string
+	script string
+}
+
+// parseHook parses a -install-hook value, "name:path".
+func parseHook(value string) (hookSpec, error) {
+	name, script, ok := strings.Cut(value, ":")
+	if !ok || script == "" {
+		return hookSpec{}, fmt.Errorf("%q is not name:path", value)
+	}
+	if !knownHooks[name] {
+		return hookSpec{}, fmt.Errorf("%q is not a git hook (see githooks(5), e.g. pre-commit or pre-push)", name)
+	}
+	return hookSpec{name: name, script: script}, nil
+}
+
+// installHook copies the script of h into the repository's hooks directory
+// and makes it executable. It returns where the hook went and whether it
+// was written; a different hook already installed under the name is left
+// alone with a warning.
+func installHook(h hookSpec) (string, bool, error) {
+	// git knows where hooks live: core.hooksPath, or the common git
+	// directory of a linked worktree
+	dest, err := gitOutput("rev-parse", "--git-path", "hooks/"+h.name)
+	if err != nil {
+		return "", false, err
+	}
+	script, err := os.ReadFile(h.script)
+	if err != nil {
+		return "", false, err
+	}
+	if existing, err := os.ReadFile(dest); err == nil {
+		if !bytes.Equal(existing, script) {
+			warnf("a different %s hook is already installed at %s; leaving it as is", h.name, dest)
+		}
+		return dest, false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", false, err
+	}
+	if err := os.WriteFile(dest, script, 0o755); err != nil {
+		return "", false, err
+	}
+	// WriteFile only applies the mode to new files, minus the umask
+	return dest, true, os.Chmod(dest, 0o755)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// runSuccessHook runs command through the shell after a successful run, with
+// details about repo exported in its environment.
+func runSuccessHook(command string, repo *github.Repository) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"REPOINIT_URL="+repo.GetHTMLURL(),
+		"REPOINIT_NAME="+repo.GetName(),
+		"REPOINIT_OWNER="+repo.GetOwner().GetLogin(),
+		"REPOINIT_SSH_URL="+repo.GetSSHURL(),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const defaultServerURL = "https://github.com"
+
+// githubServerURL is the web URL of the GitHub instance repoinit talks to,
+// and githubAPIURL its REST API URL. An empty githubAPIURL means the public
+// api.github.com.
+var (
+	githubServerURL = defaultServerURL
+	githubAPIURL    = ""
+)
+
+// configureGitHubHost selects the GitHub instance. flagURL (-github-url) wins
+// over the GITHUB_SERVER_URL and GITHUB_API_URL variables GitHub Actions
+// sets; without either, github.com is used. Unless GITHUB_API_URL says
+// otherwise, the API of a GitHub Enterprise Server lives under /api/v3.
+func configureGitHubHost(flagURL string) error {
+	server := flagURL
+	api := ""
+	if server == "" {
+		server = os.Getenv("GITHUB_SERVER_URL")
+		api = os.Getenv("GITHUB_API_URL")
+	}
+	if server == "" {
+		server = defaultServerURL
+	}
+
+	u, err := parseHTTPURL(server)
+	if err != nil {
+		return err
+	}
+	githubServerURL = strings.TrimSuffix(server, "/")
+
+	if api == "" && u.Host != "github.com" {
+		api = githubServerURL + "/api/v3"
+	}
+	if api != "" {
+		if _, err := parseHTTPURL(api); err != nil {
+			return err
+		}
+	}
+	githubAPIURL = strings.TrimSuffix(api, "/")
+	return nil
+}
+
+// parseHTTPURL parses an absolute http(s) URL.
+func parseHTTPURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub URL %q: must look like https://github.example.com", s)
+	}
+	return u, nil
+}
+
+// githubHost returns the host name of the GitHub instance, e.g. for SSH
+// remotes.
+func githubHost() string {
+	u, err := url.Parse(githubServerURL)
+	if err != nil {
+		return "github.com"
+	}
+	return u.Hostname()
+}
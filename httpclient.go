@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// httpClient is used for all HTTP traffic: the OAuth device flow directly and
+// the GitHub API as the base of the authenticated client.
+var httpClient = http.DefaultClient
+
+// newHTTPClient returns the client to use for a run configured by opts.
+func newHTTPClient(opts *options) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.insecureSkipTLSVerify {
+		warnf("TLS certificate verification is disabled (-insecure-skip-tls-verify). Anyone on the network can intercept your GitHub token; only use this against test servers.")
+		insecure := http.DefaultTransport.(*http.Transport).Clone()
+		insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		transport = insecure
+	}
+	if opts.debugHTTP {
+		transport = &debugTransport{base: transport, out: os.Stderr}
+	}
+	transport = &retryTransport{base: transport, policy: retries}
+	return &http.Client{Transport: transport}
+}
+
+// debugTransport logs each request and response line with headers. Headers
+// that carry credentials are redacted; bodies are never logged.
+type debugTransport struct {
+	base http.RoundTripper
+	out  io.Writer
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	fmt.Fprintf(t.out, "> %s %s\n", req.Method, req.URL.Redacted())
+	writeHeaders(t.out, "> ", req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(t.out, "< error after %s: %v\n", time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+	fmt.Fprintf(t.out, "< %s (%s)\n", resp.Status, time.Since(start).Round(time.Millisecond))
+	writeHeaders(t.out, "< ", resp.Header)
+	return resp, nil
+}
+
+// sensitiveHeaders are replaced by a placeholder in debug output.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+func writeHeaders(w io.Writer, prefix string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, name, value)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// defaultLabels are the labels GitHub adds to every new repository.
+var defaultLabels = map[string]bool{
+	"bug":              true,
+	"documentation":    true,
+	"duplicate":        true,
+	"enhancement":      true,
+	"good first issue": true,
+	"help wanted":      true,
+	"invalid":          true,
+	"question":         true,
+	"wontfix":          true,
+}
+
+// deleteDefaultLabels removes GitHub's default labels from owner/repo and
+// returns the names it deleted. Labels outside the default set are kept.
+func deleteDefaultLabels(ctx context.Context, client *github.Client, owner, repo string) ([]string, error) {
+	labels, err := listLabels(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, label := range labels {
+		if !defaultLabels[label.GetName()] {
+			continue
+		}
+		if _, err := client.Issues.DeleteLabel(ctx, owner, repo, label.GetName()); err != nil {
+			return deleted, fmt.Errorf("failed to delete label %q: %w", label.GetName(), err)
+		}
+		deleted = append(deleted, label.GetName())
+	}
+	return deleted, nil
+}
+
+// listLabels returns all labels of owner/repo.
+func listLabels(ctx context.Context, client *github.Client, owner, repo string) ([]*github.Label, error) {
+	var all []*github.Label
+	listOpts := &github.ListOptions{PerPage: 100}
+	for {
+		labels, resp, err := client.Issues.ListLabels(ctx, owner, repo, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list labels: %w", err)
+		}
+		all = append(all, labels...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		listOpts.Page = resp.NextPage
+	}
+}
+
+// labelSpec is a label as given by -label or -labels-from.
+type labelSpec struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+var labelColorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// validate normalizes the color (dropping a leading #, lowercasing) and
+// checks the label has a name and a six-digit hex color.
+func (l *labelSpec) validate() error {
+	l.Name = strings.TrimSpace(l.Name)
+	l.Color = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(l.Color), "#"))
+	if l.Name == "" {
+		return errors.New("label without a name")
+	}
+	if !labelColorPattern.MatchString(l.Color) {
+		return fmt.Errorf("label %q: color %q is not a six-digit hex color", l.Name, l.Color)
+	}
+	return nil
+}
+
+// parseLabel parses a -label value, "name:color" or
+// "name:color:description".
+func parseLabel(value string) (labelSpec, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 {
+		return labelSpec{}, fmt.Errorf("%q is not name:color[:description]", value)
+	}
+	l := labelSpec{Name: parts[0], Color: parts[1]}
+	if len(parts) == 3 {
+		l.Description = strings.TrimSpace(parts[2])
+	}
+	return l, l.validate()
+}
+
+// readLabels reads a -labels-from file: a JSON array of objects with name,
+// color and description, the format GitHub's label API returns.
+func readLabels(path string) ([]labelSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var labels []labelSpec
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range labels {
+		if err := labels[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return labels, nil
+}
+
+// applyLabels creates the desired labels missing from owner/repo. With sync
+// it also updates labels whose color or description differ and deletes
+// labels that aren't desired, so the repository ends up with exactly the
+// desired set. Names are compared case-insensitively, as GitHub does. It
+// returns a summary of the changes, or an unchangedStep error if there were
+// none.
+func applyLabels(ctx context.Context, client *github.Client, owner, repo string, desired []labelSpec, sync bool) (string, error) {
+	labels, err := listLabels(ctx, client, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	existing := make(map[string]*github.Label, len(labels))
+	for _, label := range labels {
+		existing[strings.ToLower(label.GetName())] = label
+	}
+
+	var created, updated, deleted int
+	wanted := make(map[string]bool, len(desired))
+	for _, d := range desired {
+		key := strings.ToLower(d.Name)
+		wanted[key] = true
+		label := &github.Label{Name: github.String(d.Name), Color: github.String(d.Color), Description: github.String(d.Description)}
+		current, ok := existing[key]
+		switch {
+		case !ok:
+			if _, _, err := client.Issues.CreateLabel(ctx, owner, repo, label); err != nil {
+				return "", fmt.Errorf("failed to create label %q: %w", d.Name, err)
+			}
+			created++
+		case sync && (current.GetName() != d.Name || !strings.EqualFold(current.GetColor(), d.Color) || current.GetDescription() != d.Description):
+			if _, _, err := client.Issues.EditLabel(ctx, owner, repo, current.GetName(), label); err != nil {
+				return "", fmt.Errorf("failed to update label %q: %w", d.Name, err)
+			}
+			updated++
+		}
+	}
+
+	if sync {
+		for _, label := range labels {
+			if wanted[strings.ToLower(label.GetName())] {
+				continue
+			}
+			if _, err := client.Issues.DeleteLabel(ctx, owner, repo, label.GetName()); err != nil {
+				return "", fmt.Errorf("failed to delete label %q: %w", label.GetName(), err)
+			}
+			deleted++
+		}
+	}
+	if created+updated+deleted == 0 {
+		return "", unchangedStep(fmt.Sprintf("all %d label(s) already in place", len(desired)))
+	}
+	if sync {
+		return fmt.Sprintf("%d created, %d updated, %d deleted", created, updated, deleted), nil
+	}
+	return fmt.Sprintf("%d created", created), nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// localPrep is what prepareLocal leaves for the rest of the run.
+type localPrep struct {
+	// resume is set when a previous run committed but failed to push.
+	resume bool
+	// envExamplePath and fundingPath are scaffolded files stagePaths
+	// doesn't pick up on its own; empty if they weren't written.
+	envExamplePath string
+	fundingPath    string
+	// codeownersPath is .github/CODEOWNERS if -default-owner wrote it.
+	codeownersPath string
+	// gitkeepPaths are the .gitkeep files written by -gitkeep.
+	gitkeepPaths []string
+}
+
+// scaffoldSources are the templates the scaffolding files are written from.
+type scaffoldSources struct {
+	// gitignores maps -gitignore-template names to their source.
+	gitignores map[string]string
+	// license is the -license text with year and holder filled in, or
+	// licenseErr why it couldn't be fetched.
+	license    string
+	licenseErr error
+}
+
+// fetchScaffolding fetches what the scaffolding files are written from. It
+// only reads, from GitHub and the working directory, so main runs it while
+// the repository is being created; prepareLocal writes the files once that
+// succeeded, leaving the directory untouched by a failed create.
+func fetchScaffolding(ctx context.Context, client *github.Client, opts *options, timings *phaseTimings) (*scaffoldSources, error) {
+	sources := &scaffoldSources{}
+
+	if len(opts.gitignoreTemplates) > 0 {
+		// An existing .gitignore kept as it is needs no templates
+		if _, err := os.Stat(".gitignore"); err != nil || opts.gitignoreMode != gitignoreKeep {
+			gitignores, err := fetchGitignoreTemplates(ctx, client, opts.gitignoreTemplates)
+			if err != nil {
+				return nil, err
+			}
+			sources.gitignores = gitignores
+		}
+	}
+
+	// With -auto-init GitHub writes LICENSE itself from the license template
+	if _, err := os.Stat("LICENSE"); opts.license != "" && !opts.autoInit && err != nil {
+		holder := opts.licenseOwner
+		if holder == "" {
+			user, _, err := client.Users.Get(ctx, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user: %w", err)
+			}
+			holder = user.GetName()
+			if holder == "" {
+				holder = user.GetLogin()
+			}
+		}
+		year := opts.licenseYear
+		if year == "" {
+			year = strconv.Itoa(time.Now().Year())
+		}
+		sources.license, sources.licenseErr = fetchLicense(ctx, client, opts.license, year, holder)
+	}
+
+	timings.mark("fetch")
+	return sources, nil
+}
+
+// prepareLocal initializes the local repository and writes the scaffolding
+// files from sources. main runs it once the repository exists, so that
+// repoName is final and a failed create doesn't leave a half-initialized
+// directory behind.
+func prepareLocal(opts *options, repoName string, sources *scaffoldSources, timings *phaseTimings) (*localPrep, error) {
+	local := &localPrep{}
+
+	// Initialize git repository locally if not already initialized
+	if !isInitialized() {
+		if err := runGit("init"); err != nil {
+			return nil, fmt.Errorf("failed to init git: %w", err)
+		}
+	}
+
+	// A previous run may have committed but failed to push; pick up where it
+	// left off instead of committing again. This is checked before origin
+	// is repointed, while its tracking branches describe what was pushed.
+	if hasCommits() && opts.subtree == "" {
+		message, _ := gitOutput("log", "-1", "--format=%B")
+		n, err := unpushedCommits()
+		if err == nil && n > 0 && isOwnCommit(message, opts.commitMessage) {
+			local.resume = true
+			fmt.Printf("Found %d unpushed commit(s) from a previous run; resuming at the push\n", n)
+		}
+	}
+
+	// Hooks go in first so they already guard the initial commit and push
+	for _, h := range opts.hooks {
+		dest, wrote, err := installHook(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install %s hook: %w", h.name, err)
+		}
+		if wrote {
+			fmt.Printf("Installed %s hook from %s at %s\n", h.name, h.script, dest)
+		}
+	}
+	timings.mark("git_init")
+
+	// On resume, the files were written and committed by the previous run
+	if local.resume {
+		timings.mark("scaffold")
+		return local, nil
+	}
+
+	if len(opts.gitignoreTemplates) > 0 {
+		if err := writeGitignoreTemplates(".gitignore", opts.gitignoreTemplates, sources.gitignores, opts.gitignoreMode); err != nil {
+			return nil, fmt.Errorf("failed to write .gitignore: %w", err)
+		}
+	}
+
+	if opts.license != "" && !opts.autoInit {
+		err := sources.licenseErr
+		if err == nil {
+			err = writeLicense("LICENSE", sources.license)
+		}
+		if errors.Is(err, errLicenseExists) {
+			fmt.Println("LICENSE exists, keeping it")
+		} else if err != nil {
+			warnf("Failed to write LICENSE: %v", err)
+		}
+	}
+
+	if opts.envExample != "" {
+		path, err := writeEnvExample(opts.envExample)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write env example: %w", err)
+		}
+		if err := ensureGitignored(".gitignore", filepath.ToSlash(opts.envExample)); err != nil {
+			return nil, fmt.Errorf("failed to update .gitignore: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", path)
+		local.envExamplePath = path
+	}
+
+	if opts.changelog {
+		wrote, err := writeChangelog("CHANGELOG.md", opts.changelogTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write CHANGELOG.md: %w", err)
+		}
+		if wrote {
+			fmt.Println("Wrote CHANGELOG.md")
+		} else {
+			fmt.Println("CHANGELOG.md already exists; leaving it as is")
+		}
+	}
+
+	if len(opts.funding) > 0 {
+		local.fundingPath = filepath.Join(".github", "FUNDING.yml")
+		if _, err := os.Stat(local.fundingPath); err == nil {
+			fmt.Printf("%s already exists; leaving it as is\n", local.fundingPath)
+		} else if err := writeFunding(local.fundingPath, opts.funding); err != nil {
+			return nil, fmt.Errorf("failed to write FUNDING.yml: %w", err)
+		} else {
+			fmt.Printf("Wrote %s\n", local.fundingPath)
+		}
+	}
+
+	// Publishing from a branch, Pages reads the domain from CNAME
+	if opts.pagesDomain != "" && !opts.noInitialCommit && opts.subtree == "" {
+		wrote, err := writeCNAME("CNAME", opts.pagesDomain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write CNAME: %w", err)
+		}
+		if wrote {
+			fmt.Println("Wrote CNAME")
+		}
+	}
+
+	if opts.defaultOwner != "" {
+		path := filepath.Join(".github", "CODEOWNERS")
+		wrote, err := writeCodeowners(path, opts.defaultOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write CODEOWNERS: %w", err)
+		}
+		if wrote {
+			fmt.Printf("Wrote %s\n", path)
+			local.codeownersPath = path
+		}
+	}
+
+	if opts.makefile != "" {
+		wrote, err := writeMakefile("Makefile", opts.makefile, repoName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write Makefile: %w", err)
+		}
+		if wrote {
+			fmt.Println("Wrote Makefile")
+		} else {
+			fmt.Println("Makefile already exists; leaving it as is")
+		}
+	}
+
+	if opts.gitkeep {
+		paths, err := writeGitkeeps(".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to write .gitkeep files: %w", err)
+		}
+		if len(paths) > 0 {
+			fmt.Printf("Wrote %d .gitkeep file(s)\n", len(paths))
+		}
+		local.gitkeepPaths = paths
+	}
+
+	timings.mark("scaffold")
+	return local, nil
+}
+
+// plannedScaffolding returns the paths prepareLocal would write or change in
+// the working directory, without touching anything, for previews. It
+// mirrors prepareLocal's checks for files that are left alone.
+func plannedScaffolding(opts *options) ([]string, error) {
+	var paths []string
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	add := func(path string) {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(opts.gitignoreTemplates) > 0 && (opts.gitignoreMode != gitignoreKeep || !exists(".gitignore")) {
+		add(".gitignore")
+	}
+	if opts.license != "" && !opts.autoInit && !exists("LICENSE") {
+		add("LICENSE")
+	}
+	if opts.envExample != "" {
+		add(opts.envExample + ".example")
+		existing, err := os.ReadFile(".gitignore")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if !gitignoreLists(string(existing), filepath.ToSlash(opts.envExample)) {
+			add(".gitignore")
+		}
+	}
+	if opts.changelog && !exists("CHANGELOG.md") {
+		add("CHANGELOG.md")
+	}
+	if funding := filepath.Join(".github", "FUNDING.yml"); len(opts.funding) > 0 && !exists(funding) {
+		add(funding)
+	}
+	if opts.pagesDomain != "" && !opts.noInitialCommit && opts.subtree == "" && !exists("CNAME") {
+		add("CNAME")
+	}
+	if opts.defaultOwner != "" {
+		path := filepath.Join(".github", "CODEOWNERS")
+		existing, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if _, ok := defaultOwnerRule(string(existing)); !ok {
+			add(path)
+		}
+	}
+	if opts.makefile != "" && !exists("Makefile") {
+		add("Makefile")
+	}
+	if opts.gitkeep {
+		keeps, err := gitkeepPaths(".")
+		if err != nil {
+			return nil, err
+		}
+		for _, keep := range keeps {
+			add(keep)
+		}
+	}
+	for i, path := range paths {
+		paths[i] = filepath.ToSlash(path)
+	}
+	return paths, nil
+}
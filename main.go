@@ -4,6 +4,7 @@ import (
     "context"
     "encoding/json"
     "errors"
+    "flag"
     "fmt"
     "io"
     "log"
@@ -12,126 +13,822 @@ import (
     "os"
     "os/exec"
     "path/filepath"
+    "slices"
     "strings"
     "time"
 
     "github.com/google/go-github/v57/github"
     "github.com/joho/godotenv"
-    "golang.org/x/oauth2"
 )
 
 func main() {
-	// Load .env file if it exists
-	godotenv.Load()
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			godotenv.Load()
+			if err := configureGitHubHost(""); err != nil {
+				log.Fatal(err)
+			}
+			configureUserAgent("")
+			if err := run(os.Args[2:]); err != nil {
+				if errors.Is(err, flag.ErrHelp) {
+					os.Exit(0)
+				}
+				if errors.Is(err, errUsage) {
+					os.Exit(2)
+				}
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	colorMode = opts.color
+	if opts.json || opts.print != "" {
+		reserveStdout()
+	}
+	jsonErrors = opts.json
+	retries = retryPolicy{maxRetries: opts.maxRetries, baseDelay: opts.retryBaseDelay}
+	httpClient = newHTTPClient(opts)
+
+	// Load .env file if it exists; a file given with -env-file has to load
+	var envFile string
+	if opts.envFile != "" {
+		if err := godotenv.Load(opts.envFile); err != nil {
+			fatalf("Failed to load %s: %v", opts.envFile, err)
+		}
+		envFile = opts.envFile
+	} else if !opts.noEnvFile && godotenv.Load() == nil {
+		envFile = ".env"
+	}
+
+	if err := configureGitHubHost(opts.githubURL); err != nil {
+		fatal(err)
+	}
+	configureUserAgent(opts.userAgent)
+
+	// Without a .git of its own, the current directory gets a new repository
+	// nested inside whatever repository it happens to be in, which the outer
+	// one then sees as an untracked directory. -subtree is meant for exactly
+	// that situation and doesn't touch the repository.
+	nested := false
+	if opts.subtree == "" {
+		top, err := enclosingWorktree()
+		if err != nil {
+			fatal("Failed to check for an enclosing repository:", err)
+		}
+		if top != "" && opts.force {
+			warnf("The current directory is inside the git repository at %s; creating a nested repository inside it (-force)", top)
+		} else if top != "" {
+			fatalf("The current directory is inside the git repository at %s, so repoinit would create a nested repository inside %s. Run it from %s (with -subtree to publish only this directory), or pass -force to proceed anyway", top, top, top)
+		}
+		nested = top != ""
+	}
+
+	// Committing everything would sweep work in progress into the commit.
+	// A nested repository is yet to be created, so there is nothing to
+	// check; the enclosing repository's state is none of its business.
+	if opts.refuseDirty && !nested && hasCommits() {
+		changes, err := uncommittedChanges()
+		if err != nil {
+			fatal("Failed to get git status:", err)
+		}
+		if changes != "" {
+			fatalf("Refusing to continue with uncommitted changes (-refuse-dirty); commit or stash them first:\n%s", changes)
+		}
+	}
+
+    timings := newPhaseTimings()
 
     // Resolve GitHub token via env, config file, gh CLI, or OAuth device flow
     ctx := context.Background()
-    token, err := resolveGitHubToken(ctx)
+    token, tokenSource, err := resolveGitHubToken(ctx, opts)
     if err != nil || token == "" {
-        log.Fatalf("Authentication required. %v", err)
+        fatalf("Authentication required. %v", err)
+    }
+    timings.mark("auth")
+    if opts.showTokenSource {
+        log.Printf("Using token from: %s", tokenSource)
     }
 
 	// Get current directory name
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal("Failed to get current directory:", err)
+		fatal("Failed to get current directory:", err)
 	}
 	repoName := filepath.Base(pwd)
 
     // Initialize GitHub client
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client := newGitHubClient(ctx, token)
+
+	// Repositories are created under the organization if one was given,
+	// otherwise under the authenticated user
+	org := opts.org
+	if opts.owner != "" {
+		org, err = resolveOwner(ctx, client, opts.owner)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	owner := org
+	if owner == "" {
+		user, _, err := client.Users.Get(ctx, "")
+		if err != nil {
+			fatal("Failed to get user:", err)
+		}
+		owner = user.GetLogin()
+	}
+
+	if opts.checkName {
+		available, err := checkNameAvailable(ctx, client, owner, repoName)
+		if err != nil {
+			fatal("Failed to check repository name:", err)
+		}
+		if !available {
+			fmt.Printf("%s/%s is taken\n", owner, repoName)
+			os.Exit(exitNameTaken)
+		}
+		fmt.Printf("%s/%s is available\n", owner, repoName)
+		os.Exit(exitNameAvailable)
+	}
+
+	if opts.orgRepoDefaults && org == "" {
+		warnf("-org-repo-defaults only applies to organization repositories")
+	} else if opts.orgRepoDefaults {
+		inherited, err := inheritOrgDefaults(ctx, client, org, opts)
+		if err != nil {
+			fatal(err)
+		}
+		if len(inherited) > 0 {
+			fmt.Printf("Inherited from %s: %s\n", org, strings.Join(inherited, ", "))
+		} else {
+			fmt.Printf("No repository defaults to inherit from %s\n", org)
+		}
+	}
+
+	internalDefault := org != "" && orgInternalDefault(opts.orgInternalDefault)
+	visibility := opts.visibility
+	if visibility == "" {
+		visibility = visibilityPublic
+		if internalDefault {
+			visibility = visibilityInternal
+		}
+	}
+	if visibility == visibilityInternal && org == "" {
+		fatal("Internal repositories can only be created in an organization")
+	}
+
+	if org != "" {
+		if err := checkOrgAllowsVisibility(ctx, client, org, visibility); err != nil {
+			fatal(err)
+		}
+	}
+
+	// Check the secrets exist before there is a repository to add to them
+	if len(opts.orgSecrets) > 0 {
+		if org == "" {
+			fatal("-org-secret only applies to organization repositories")
+		}
+		if _, err := getOrgSecrets(ctx, client, org, opts.orgSecrets); err != nil {
+			fatal(err)
+		}
+	}
+
+	if opts.dryRun {
+		paths := opts.manifestPaths
+		if paths == nil {
+			if paths, err = stagePaths(); err != nil {
+				fatal("Failed to read directory:", err)
+			}
+		}
+		if opts.json {
+			plan, err := buildPlan(ctx, client, opts, owner, org, repoName, visibility, paths)
+			if err != nil {
+				fatal("Failed to preview initial commit:", err)
+			}
+			if err := writeJSON(plan); err != nil {
+				fatal("Failed to write JSON output:", err)
+			}
+			return
+		}
+		if err := printDryRun(opts, owner, repoName, paths); err != nil {
+			fatal("Failed to preview initial commit:", err)
+		}
+		return
+	}
+
+	// Where internal is the policy, going public has to be deliberate
+	if internalDefault && visibility == visibilityPublic && isInteractive() {
+		answer, err := prompt(fmt.Sprintf("Create %s/%s as a PUBLIC repository? [y/N] ", owner, repoName))
+		if err != nil || !strings.EqualFold(answer, "y") {
+			fatal("Aborted")
+		}
+	}
+
+	// A forgotten description is easy to add now and easy to forget later;
+	// scripted runs are never asked
+	if opts.promptDescription && opts.description == "" && !opts.json && opts.print == "" && isInteractive() {
+		answer, err := prompt("Description (Enter to skip): ")
+		if err != nil {
+			fatal("Failed to read description:", err)
+		}
+		if opts.expandEmoji {
+			answer = expandEmoji(answer)
+		}
+		opts.description = answer
+	}
 
 	// Create repository
-	repo := &github.Repository{
-		Name:     github.String(repoName),
-		Private:  github.Bool(false),
-		AutoInit: github.Bool(false),
+	settings := repoSettings(opts)
+	spec := &github.Repository{}
+	if settings != nil {
+		*spec = *settings
+	}
+	spec.Name = github.String(repoName)
+	spec.Private = github.Bool(visibility != visibilityPublic)
+	if org != "" {
+		spec.Visibility = github.String(visibility)
+	}
+	spec.AutoInit = github.Bool(opts.autoInit)
+	if opts.license != "" {
+		spec.LicenseTemplate = github.String(strings.ToLower(opts.license))
 	}
 
-	repo, resp, err := client.Repositories.Create(ctx, "", repo)
+	// On a re-run origin already points at the repository, which is then
+	// used as it is instead of attempting to create it. Only a repository
+	// of the owner counts, so publishing a clone of someone else's project
+	// still creates a new one.
+	var existing *github.Repository
+	if opts.reuse && opts.subtree == "" {
+		if originOwner, originName, ok := originRepo(); ok && strings.EqualFold(originOwner, owner) {
+			if r, _, err := client.Repositories.Get(ctx, originOwner, originName); err == nil {
+				existing = r
+				repoName = r.GetName()
+				spec.Name = github.String(repoName)
+			}
+		}
+	}
+
+	// The templates for the scaffolding files are fetched before the
+	// repository is created, so an unknown template name fails the run
+	// without leaving an empty repository behind. Fetching only reads;
+	// nothing is written to the directory until the create succeeded.
+	sources, err := fetchScaffolding(ctx, client, opts, timings)
 	if err != nil {
-		if resp != nil && resp.StatusCode == 422 { // HTTP 422 Unprocessable Entity typically means repo exists
-			// Get authenticated user
-			user, _, err := client.Users.Get(ctx, "")
+		fatal(err)
+	}
+	createFailed := fatal
+
+	created := false
+	create := func() (*github.Repository, error) {
+		if opts.template != "" {
+			repo, _, err := createFromTemplate(ctx, client, opts.template, owner, spec)
+			return repo, err
+		}
+		repo, _, err := client.Repositories.Create(ctx, org, spec)
+		return repo, err
+	}
+	// reuseExisting applies -rename and the repository settings to an
+	// existing repository
+	reuseExisting := func(repo *github.Repository) *github.Repository {
+		if opts.rename != "" && opts.rename != repo.GetName() {
+			renamed, err := renameRepo(ctx, client, repo, opts.rename)
 			if err != nil {
-				log.Fatal("Failed to get user:", err)
+				createFailed(err)
+			}
+			repo = renamed
+			successf("Renamed repository: %s", repo.GetHTMLURL())
+		}
+
+		if settings != nil && opts.noEditExisting {
+			fmt.Println("Not changing settings of the existing repository (-no-edit-existing)")
+		} else if settings != nil {
+			edited, err := applyRepoSettings(ctx, client, repo, settings)
+			if err != nil {
+				warnf("%v", err)
+			}
+			repo = edited
+		}
+		return repo
+	}
+	var repo *github.Repository
+	if existing == nil {
+		repo, err = create()
+	}
+	// When the name is taken and reuse is off, let an interactive user pick
+	// another name instead of failing
+	for isNameTaken(err) && !opts.reuse && isInteractive() {
+		newName, promptErr := promptNewRepoName(ctx, client, owner, repoName)
+		if promptErr != nil {
+			createFailed(fmt.Sprintf("Repository %s already exists: %v", repoName, promptErr))
+		}
+		repoName = newName
+		spec.Name = github.String(repoName)
+		repo, err = create()
+	}
+	if existing != nil {
+		successf("Using existing repository from origin: %s", existing.GetHTMLURL())
+		repo = reuseExisting(existing)
+	} else if err != nil {
+		if isNameTaken(err) {
+			if !opts.reuse {
+				createFailed(fmt.Sprintf("Repository %s already exists and --reuse=false was given", repoName))
 			}
 
 			// Try to get the existing repo
-			repo, _, err = client.Repositories.Get(ctx, *user.Login, repoName)
+			repo, _, err = client.Repositories.Get(ctx, owner, repoName)
 			if err != nil {
-				log.Fatal("Failed to get existing repository:", err)
+				createFailed("Failed to get existing repository:", explainSSO(err))
 			}
-			fmt.Printf("Using existing repository: %s\n", *repo.HTMLURL)
+			successf("Using existing repository: %s", *repo.HTMLURL)
+			// A repository renamed before is found through the redirect
+			// from its old name
+			if repo.GetName() != repoName {
+				fmt.Printf("%s/%s was renamed to %s\n", owner, repoName, repo.GetFullName())
+			}
+			repo = reuseExisting(repo)
 		} else {
-			log.Fatal("Failed to create repository:", err)
+			createFailed("Failed to create repository:", explainSSO(err))
 		}
 	} else {
-		fmt.Printf("Created repository: %s\n", *repo.HTMLURL)
-	}
+		created = true
+		successf("Created repository: %s", *repo.HTMLURL)
+		if opts.rename != "" {
+			warnf("-rename only applies to an existing repository; %s was created under this directory's name", repo.GetFullName())
+		}
 
-	// Initialize git repository locally if not already initialized
-	if _, err := os.Stat(".git"); os.IsNotExist(err) {
-		if err := execCmd("git", "init"); err != nil {
-			log.Fatal("Failed to init git:", err)
+		// Generating from a template only takes name, description and
+		// visibility, and creating ignores allow_update_branch; those
+		// settings are applied afterwards
+		if settings != nil && (opts.template != "" || (opts.allowUpdateBranch && !repo.GetAllowUpdateBranch())) {
+			repo, err = applyRepoSettings(ctx, client, repo, settings)
+			if err != nil {
+				warnf("%v", err)
+			}
 		}
 	}
+	timings.mark("create")
+
+	local, err := prepareLocal(opts, repo.GetName(), sources, timings)
+	if err != nil {
+		fatal(err)
+	}
+	resume, envExamplePath, fundingPath := local.resume, local.envExamplePath, local.fundingPath
 
-	// Check if remote exists and remove it if it does
-	removeCmd := exec.Command("git", "remote", "remove", "origin")
-	removeCmd.Run() // ignore errors since remote might not exist
+	// -assume-default-branch stands in for what GitHub reports
+	defaultBranch := repo.GetDefaultBranch()
+	if opts.assumeDefaultBranch != "" {
+		defaultBranch = opts.assumeDefaultBranch
+	}
 
-	// Add remote
-	remoteURL := fmt.Sprintf("git@github.com:%s.git", *repo.FullName)
-	if err := execCmd("git", "remote", "add", "origin", remoteURL); err != nil {
-		log.Fatal("Failed to add remote:", err)
+	// Point origin at the repository, adding it if it doesn't exist yet.
+	// -subtree publishes part of a repository that has its own origin, so
+	// it pushes to the URL instead.
+	remoteURL := repoRemoteURL(repo)
+	remote := "origin"
+	if opts.subtree != "" {
+		remote = remoteURL
+	} else if err := setRemote("origin", remoteURL); err != nil {
+		fatal("Failed to set remote:", err)
 	}
 
-	// Add .gitignore first if it exists
-	if _, err := os.Stat(".gitignore"); err == nil {
-		if err := execCmd("git", "add", ".gitignore"); err != nil {
-			log.Printf("Warning: Failed to add .gitignore: %v", err)
+	onRemoteBase := false
+	var subtreeCommit string
+	if opts.subtree != "" {
+		commitMessage := initialCommitMessage
+		if opts.commitMessage != "" {
+			commitMessage = opts.commitMessage
+		}
+		subtreeCommit, err = commitSubtree(opts.subtree, withCoAuthors(commitMessage, opts.coAuthors))
+		if err != nil {
+			fatal(err)
+		}
+		timings.mark("commit")
+	} else if !opts.noInitialCommit && !resume {
+		// Submodules are committed as references to commits in their own
+		// repositories, so they have to be checked out
+		if opts.initSubmodules {
+			if err := initSubmodules(); err != nil {
+				warnf("Failed to initialize submodules: %v", err)
+			}
+		}
+		if subs, err := declaredSubmodules(); err != nil {
+			fatal("Failed to read .gitmodules:", err)
+		} else if len(subs) > 0 {
+			checkedOut := 0
+			for _, sub := range subs {
+				if sub.checkedOut {
+					checkedOut++
+				} else {
+					warnf("submodule %s isn't checked out, so the initial commit can't reference it; check it out (or pass -init-submodules) and run repoinit again", sub.path)
+				}
+			}
+			if checkedOut > 0 {
+				fmt.Printf("Committing %d submodule(s) as references; their contents live in their own repositories\n", checkedOut)
+			}
+		}
+
+		// Add .gitignore first, then everything that isn't hidden or
+		// ignored, unless a manifest says exactly what to add
+		paths := opts.manifestPaths
+		if paths == nil && opts.stageMode != stageTracked {
+			if paths, err = stagePaths(); err != nil {
+				fatal("Failed to read directory:", err)
+			}
+			// The generated files that are hidden (.github) or in hidden
+			// directories aren't picked up by stagePaths
+			paths = appendUncovered(paths, envExamplePath, fundingPath, local.codeownersPath)
+			paths = appendUncovered(paths, local.gitkeepPaths...)
 		}
+		if opts.stageMode == stageInteractive {
+			if paths, err = selectPaths(paths); err != nil {
+				fatal("Failed to read answer:", err)
+			}
+		}
+		if envFile != "" && slices.ContainsFunc(paths, func(p string) bool { return sameFile(p, envFile) }) {
+			warnf("%s was loaded for this run and is about to be committed; remove it from -manifest if it holds secrets", envFile)
+		}
+
+		// GitHub created the root commit; build on it rather than on an
+		// unrelated local one
+		commitMessage := initialCommitMessage
+		if opts.autoInit && created && !hasCommits() {
+			if err := adoptRemoteBranch(defaultBranch, opts.cloneDepth); err != nil {
+				fatal(err)
+			}
+			commitMessage = projectFilesCommitMessage
+			onRemoteBase = true
+		}
+
+		// Likewise for a generated repository, whose contents GitHub
+		// copies in the background
+		if opts.template != "" && created && !hasCommits() {
+			err := retries.do("Fetch template contents", func() error {
+				return adoptRemoteBranch(defaultBranch, opts.cloneDepth)
+			})
+			if err != nil {
+				fatal(err)
+			}
+			if err := restoreMissingFiles(); err != nil {
+				fatal("Failed to check out template files:", err)
+			}
+			if len(opts.templateExcludes) > 0 {
+				removed, err := removeTemplateFiles(opts.templateExcludes)
+				if err != nil {
+					fatal(err)
+				}
+				if len(removed) > 0 {
+					fmt.Printf("Removed %d excluded template file(s)\n", len(removed))
+					commitWithFallback(excludedTemplateFilesMessage, opts.allowUnsigned)
+				}
+			}
+			commitMessage = projectFilesCommitMessage
+			onRemoteBase = true
+		}
+		if opts.commitMessage != "" {
+			commitMessage = opts.commitMessage
+		}
+
+		if opts.stageMode == stageTracked {
+			if err := stageTrackedFiles(); err != nil {
+				fatal("Failed to stage tracked files:", err)
+			}
+		} else {
+			stageFiles(paths)
+		}
+		if opts.verbose || opts.listFiles {
+			staged, err := stagedFiles()
+			if err != nil {
+				fatal("Failed to list staged files:", err)
+			}
+			printStagedFiles(staged, opts.listFiles)
+		}
+		timings.mark("stage")
+
+		if opts.scanSecrets != "" {
+			findings, err := scanStagedSecrets(opts.secretRuleSet)
+			if err != nil {
+				fatal("Failed to scan staged files for secrets:", err)
+			}
+			for _, f := range findings {
+				warnf("possible secret: %s", f)
+			}
+			if len(findings) > 0 && opts.scanSecrets == scanSecretsBlock {
+				fatalf("Found %d possible secret(s) in the files to commit, so nothing was committed or pushed; remove them (they are still staged) and run repoinit again, or rerun with -scan-secrets=warn", len(findings))
+			}
+			timings.mark("scan_secrets")
+		}
+
+		// Commit
+		if onRemoteBase && !hasStagedChanges() {
+			fmt.Println("Nothing to commit on top of the initial commit created by GitHub")
+		} else if opts.stageMode == stageTracked && !hasStagedChanges() {
+			if !hasCommits() {
+				fatal("Nothing to commit: -stage-mode tracked only stages files git already tracks, and there are none")
+			}
+			fmt.Println("No changes to tracked files; pushing the existing commits")
+		} else if opts.splitCommit {
+			groups, err := stagedGroups()
+			if err != nil {
+				fatal("Failed to list staged files:", err)
+			}
+			for i, g := range groups {
+				commitWithFallback(withCoAuthors(groupCommitMessage(g, commitMessage, i == 0), opts.coAuthors), opts.allowUnsigned, g.paths...)
+			}
+		} else {
+			commitWithFallback(withCoAuthors(commitMessage, opts.coAuthors), opts.allowUnsigned)
+		}
+		timings.mark("commit")
 	}
 
-	// Add all non-hidden files
-	files, err := os.ReadDir(".")
+	// Get current branch name; unlike rev-parse this works before the
+	// first commit
+	currentBranch, err := gitOutput("symbolic-ref", "--short", "HEAD")
 	if err != nil {
-		log.Fatal("Failed to read directory:", err)
+		fatal("Failed to get branch name:", err)
+	}
+
+	// The subtree commit isn't on any local branch; it's pushed to the
+	// repository's default branch
+	if subtreeCommit != "" && defaultBranch != "" {
+		currentBranch = defaultBranch
+	}
+
+	nothingToPush := opts.noInitialCommit && !hasCommits()
+	if nothingToPush {
+		warnf("Nothing to push: %s has no commits yet", currentBranch)
 	}
 
-	for _, file := range files {
-		name := file.Name()
-		if !strings.HasPrefix(name, ".") && !file.IsDir() && name != ".gitignore" {
-			if err := execCmd("git", "add", name); err != nil {
-				log.Printf("Warning: Failed to add %s: %v", name, err)
+	// An existing repository that already has its default branch would get
+	// a second, stray branch if we pushed a differently named local branch.
+	// An assumed default branch is taken to exist without asking GitHub.
+	if !nothingToPush && subtreeCommit == "" && (!created || opts.autoInit || opts.template != "") && defaultBranch != "" && defaultBranch != currentBranch {
+		exists := opts.assumeDefaultBranch != ""
+		if !exists {
+			if exists, err = branchExists(ctx, client, repo.GetOwner().GetLogin(), repo.GetName(), defaultBranch); err != nil {
+				fatal("Failed to check default branch:", err)
 			}
 		}
+		if exists {
+			if !opts.renameExisting {
+				fatalf("Local branch %s doesn't match the default branch %s of %s; rename it with `git branch -m %s` or pass -rename-existing", currentBranch, defaultBranch, repo.GetFullName(), defaultBranch)
+			}
+			if err := runGit("branch", "-m", currentBranch, defaultBranch); err != nil {
+				fatal("Failed to rename branch:", err)
+			}
+			fmt.Printf("Renamed local branch %s to %s\n", currentBranch, defaultBranch)
+			currentBranch = defaultBranch
+		}
 	}
 
-	// Commit
-	if err := execCmd("git", "commit", "-m", "Initial commit"); err != nil {
-		log.Fatal("Failed to commit:", err)
+	// Local history that predates -auto-init still has to be rebased onto
+	// the commit GitHub created
+	if !nothingToPush && ((opts.syncExisting && !created) || ((opts.autoInit || opts.template != "") && created && !onRemoteBase)) {
+		if err := syncWithRemote(currentBranch); err != nil {
+			fatal(err)
+		}
 	}
 
-	// Get current branch name
-	branchCmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	branchBytes, err := branchCmd.Output()
-	if err != nil {
-		log.Fatal("Failed to get branch name:", err)
+	// -branch moves the work from the default branch onto the working
+	// branch, or with -keep-default-branch starts the working branch at the
+	// same commit. The post-create steps still apply to the default branch,
+	// which is the working branch itself only when it's the first one pushed
+	// to a new repository.
+	stepBranch := currentBranch
+	var extraBranches []string
+	if opts.branch != "" && !nothingToPush && currentBranch != opts.branch {
+		if currentBranch != defaultBranch && currentBranch != initDefaultBranch() {
+			warnf("-branch: %s isn't the default branch; pushing it as it is", currentBranch)
+		} else if opts.keepDefaultBranch {
+			if err := runGit("switch", "-c", opts.branch); err != nil {
+				fatalf("Failed to create branch %s: %v", opts.branch, err)
+			}
+			fmt.Printf("Created working branch %s from %s\n", opts.branch, currentBranch)
+			extraBranches = append(extraBranches, opts.branch)
+		} else {
+			if err := runGit("branch", "-m", currentBranch, opts.branch); err != nil {
+				fatalf("Failed to rename branch %s to %s: %v", currentBranch, opts.branch, err)
+			}
+			fmt.Printf("Renamed local branch %s to working branch %s\n", currentBranch, opts.branch)
+			if created && !onRemoteBase {
+				stepBranch = opts.branch
+			}
+			currentBranch = opts.branch
+		}
+	}
+
+	// Tag after syncing, which may have rewritten the commit
+	pushRefs := append([]string{currentBranch}, extraBranches...)
+	if opts.tagInitial != "" && !nothingToPush {
+		message := opts.tagMessage
+		if opts.tagAnnotated && message == "" {
+			message = opts.tagInitial
+		}
+		if err := createTag(opts.tagInitial, message); err != nil {
+			fatalf("Failed to create tag %s: %v", opts.tagInitial, err)
+		}
+		pushRefs = append(pushRefs, "refs/tags/"+opts.tagInitial)
+	}
+
+	// -since publishes a rewritten copy of the branch. The local branch keeps
+	// its full history, so it can't track the remote one.
+	// With -push-concurrency the tags follow the branch in parallel pushes
+	concurrentTags := opts.pushConcurrency > 1 && !opts.noPush
+	tagArgs := tagPushArgs[opts.tagPushMode]
+	if concurrentTags {
+		tagArgs = nil
+	}
+	pushArgs := append([]string{"push", "-u"}, tagArgs...)
+	if opts.recurseSubmodules {
+		pushArgs = append(pushArgs, "--recurse-submodules=on-demand")
+	}
+	pushArgs = append(pushArgs, remote)
+	pushed := "HEAD"
+	if opts.since != "" && !nothingToPush {
+		tip, err := commitSince(opts.since)
+		if err != nil {
+			fatal("Failed to prepare history for -since:", err)
+		}
+		warnf("-since: only the history from %s on is published; earlier commits will not be in %s. Your local %s keeps its full history and won't track the remote branch.", opts.since, repo.GetFullName(), currentBranch)
+		pushArgs = []string{"push", remote}
+		pushRefs[0] = tip + ":refs/heads/" + currentBranch
+		pushed = tip
+	}
+	if subtreeCommit != "" {
+		fmt.Printf("-subtree: publishing %s as the root of %s in a fresh commit, without the history of the local repository\n", opts.subtree, repo.GetFullName())
+		pushArgs = []string{"push", remote}
+		pushRefs[0] = subtreeCommit + ":refs/heads/" + currentBranch
+		pushed = subtreeCommit
 	}
-	currentBranch := strings.TrimSpace(string(branchBytes))
 
 	// Push
-	if err := execCmd("git", "push", "-u", "origin", currentBranch); err != nil {
-		log.Fatal("Failed to push:", err)
+	branchPushed := !nothingToPush && !opts.noPush
+	if opts.noPush && !nothingToPush {
+		fmt.Printf("Not pushing %s (-no-push); push it with: git %s\n", currentBranch, strings.Join(append(pushArgs, pushRefs...), " "))
 	}
+	if branchPushed {
+		push := func() error { return runGit(append(pushArgs, pushRefs...)...) }
+		if err := retries.do("Push", push); err != nil {
+			fatal("Failed to push:", err)
+		}
 
-	fmt.Println("Successfully initialized and pushed repository!")
+		// Catch partial pushes and server hooks that rewrote or dropped commits
+		if !opts.noVerifyPush {
+			local, err := gitOutput("rev-parse", pushed)
+			if err != nil {
+				fatal("Failed to verify push:", err)
+			}
+			head, err := remoteBranchHead(remote, currentBranch)
+			if err != nil {
+				fatal("Failed to verify push:", err)
+			}
+			if head != local {
+				fatalf("Push verification failed: %s on %s is at %s, but %s was pushed (skip this check with -no-verify-push)", currentBranch, repo.GetFullName(), head, local)
+			}
+		}
+
+		if concurrentTags {
+			tags, err := localTags()
+			if err != nil {
+				fatal("Failed to list tags:", err)
+			}
+			if err := pushConcurrently(remote, tags, opts.pushConcurrency); err != nil {
+				fatal("Failed to push tags:", err)
+			}
+			fmt.Printf("Pushed %d tag(s)\n", len(tags))
+		}
+	}
+	timings.mark("push")
+
+	results := runSteps(postCreateSteps(ctx, client, opts, repo, org, stepBranch, created, branchPushed))
+	timings.mark("post_create")
+
+	// Once GitHub knows the default branch, origin/HEAD can follow it, so
+	// checking out the default branch isn't ambiguous
+	if branchPushed && remote == "origin" {
+		head, err := updateRemoteHead(remote)
+		switch {
+		case err != nil:
+			warnf("Failed to update origin/HEAD: %v", err)
+		case opts.remoteHead != "" && head != opts.remoteHead:
+			warnf("origin/HEAD points at %s, not %s", head, opts.remoteHead)
+		case opts.verbose:
+			fmt.Printf("origin/HEAD points at %s\n", head)
+		}
+	}
+	printStepSummary(results)
+	pagesLiveURL := succeededStepDetail(results, pagesBuildStep)
+	if pagesLiveURL != "" {
+		successf("GitHub Pages site is live: %s", pagesLiveURL)
+	}
+
+	reportRepoSettings(opts, repo)
+
+	var rateLimit *rateLimitStatus
+	if opts.printRateLimit {
+		if rateLimit, err = fetchRateLimit(ctx, client); err != nil {
+			warnf("%v", err)
+		} else {
+			printRateLimit(rateLimit)
+		}
+	}
+
+	if opts.print != "" {
+		if err := printField(repo, opts.print); err != nil {
+			fatal("Failed to write output:", err)
+		}
+	}
+	var timingsMS map[string]int64
+	if opts.timings {
+		timings.print()
+		timingsMS = timings.milliseconds()
+	}
+
+	if opts.json {
+		err := writeJSON(runResult{
+			Name:      repo.GetName(),
+			Owner:     repo.GetOwner().GetLogin(),
+			URL:       repo.GetHTMLURL(),
+			SSHURL:    repo.GetSSHURL(),
+			CloneURL:  repo.GetCloneURL(),
+			Created:   created,
+			Branch:    currentBranch,
+			Steps:     results,
+			PagesURL:  pagesLiveURL,
+			RateLimit: rateLimit,
+			Timings:   timingsMS,
+		})
+		if err != nil {
+			fatal("Failed to write JSON output:", err)
+		}
+	}
+
+	if failed := failedRequiredSteps(results); failed > 0 {
+		if branchPushed {
+			fatalf("Repository was pushed, but %d post-create step(s) failed", failed)
+		}
+		fatalf("Repository was set up, but %d post-create step(s) failed", failed)
+	}
+	if nothingToPush {
+		successf("Repository created and remote added; push once you have commits")
+	} else if opts.noPush {
+		successf("Repository created and configured; push when you're ready")
+	} else {
+		successf("Successfully initialized and pushed repository!")
+	}
+
+	if opts.onSuccess != "" {
+		if err := runSuccessHook(opts.onSuccess, repo); err != nil {
+			warnf("--on-success command failed: %v", err)
+		}
+	}
+}
+
+// repoRemoteURL returns the SSH URL GitHub reports for repo. The API
+// resolves renames and transfers, so this is where the repository actually
+// lives even if it was reached under an old owner or name.
+func repoRemoteURL(repo *github.Repository) string {
+	if url := repo.GetSSHURL(); url != "" {
+		return url
+	}
+	return fmt.Sprintf("git@%s:%s.git", githubHost(), repo.GetFullName())
+}
+
+// sameFile reports whether paths a and b name the same existing file.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	return err == nil && os.SameFile(fa, fb)
+}
+
+// commitWithFallback commits the index with message, or only paths if given,
+// exiting on failure. If signing fails and allowUnsigned is set it commits
+// without a signature.
+func commitWithFallback(message string, allowUnsigned bool, paths ...string) {
+	err := commitPaths(message, paths)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, errSigningFailed) {
+		fatal("Failed to commit:", err)
+	}
+	if !allowUnsigned {
+		fatal(signingFailureHelp())
+	}
+	warnf("Signing the commit failed; committing without a signature (-allow-unsigned)")
+	if err := commitUnsigned(message, paths); err != nil {
+		fatal("Failed to commit:", err)
+	}
 }
 
 func execCmd(name string, args ...string) error {
@@ -141,34 +838,88 @@ func execCmd(name string, args ...string) error {
 	return cmd.Run()
 }
 
+// Token sources reported by resolveGitHubToken.
+const (
+    tokenSourcePass       = "pass"
+    tokenSourceOp         = "1password"
+    tokenSourceEnv        = "env"
+    tokenSourceConfig     = "config"
+    tokenSourceGh         = "gh"
+    tokenSourceGhStored   = "gh-stored"
+    tokenSourceDeviceFlow = "device-flow"
+)
+
 // resolveGitHubToken attempts to find or obtain a GitHub token in the following order:
+// 0) a password manager entry explicitly given on the command line (--token-from-pass, --token-from-op)
 // 1) GITHUB_TOKEN env var
 // 2) token stored at ~/.config/repoinit/token
-// 3) gh CLI (gh auth token or gh auth login --web)
+// 3) gh CLI (gh auth token, the token gh stored, or gh auth login --web)
 // 4) OAuth Device Flow using GITHUB_OAUTH_CLIENT_ID
-func resolveGitHubToken(ctx context.Context) (string, error) {
+// Alongside the token it returns which of these sources provided it.
+func resolveGitHubToken(ctx context.Context, opts *options) (token, source string, err error) {
+    // 0) - 2)
+    if token, source, err := findGitHubToken(opts); err != nil || token != "" {
+        return token, source, err
+    }
+
+    // 3) and 4)
+    return loginToken(ctx, opts.deviceFlowTimeout, true)
+}
+
+// findGitHubToken looks up a token from the sources that don't need user
+// interaction (0-2 of resolveGitHubToken). It returns an empty token if none
+// of them has one.
+func findGitHubToken(opts *options) (token, source string, err error) {
+    // 0) explicitly requested password manager; tokens from here are never
+    // written to the config file
+    if opts.tokenFromPass != "" {
+        token, err := tryPassToken(opts.tokenFromPass)
+        if err != nil {
+            return "", "", err
+        }
+        return token, tokenSourcePass, nil
+    }
+    if opts.tokenFromOp != "" {
+        token, err := tryOpToken(opts.tokenFromOp)
+        if err != nil {
+            return "", "", err
+        }
+        return token, tokenSourceOp, nil
+    }
+
     // 1) env var
     envToken := strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
     if envToken != "" {
-        return envToken, nil
+        return envToken, tokenSourceEnv, nil
     }
 
     // 2) config file
     if token, _ := readStoredToken(); token != "" {
-        return token, nil
+        return token, tokenSourceConfig, nil
     }
 
+    return "", "", nil
+}
+
+// loginToken obtains a new token via the gh CLI or, failing that, the OAuth
+// device flow, and stores it for next time. With reuseGhSession, a token from
+// an existing gh login is taken as is; otherwise gh is asked to log in anew.
+func loginToken(ctx context.Context, deviceFlowTimeout time.Duration, reuseGhSession bool) (token, source string, err error) {
     // 3) gh CLI
-    if token, err := tryGhToken(); err == nil && token != "" {
+    if token, err := tryGhToken(); reuseGhSession && err == nil && token != "" {
         // Persist for next time
         _ = writeStoredToken(token)
-        return token, nil
+        return token, tokenSourceGh, nil
+    } else if token, _, err := tryGhStoredToken(githubHost()); reuseGhSession && err == nil {
+        // gh can't be run, but the login it stored is still usable
+        _ = writeStoredToken(token)
+        return token, tokenSourceGhStored, nil
     } else {
         // Attempt interactive gh login if available
         if err := tryGhWebLogin(); err == nil {
             if token, err := tryGhToken(); err == nil && token != "" {
                 _ = writeStoredToken(token)
-                return token, nil
+                return token, tokenSourceGh, nil
             }
         }
     }
@@ -176,17 +927,17 @@ func resolveGitHubToken(ctx context.Context) (string, error) {
     // 4) OAuth Device Flow
     clientID := strings.TrimSpace(os.Getenv("GITHUB_OAUTH_CLIENT_ID"))
     if clientID != "" {
-        token, err := runDeviceFlow(ctx, clientID, []string{"repo"})
+        token, err := runDeviceFlow(ctx, clientID, []string{"repo"}, deviceFlowTimeout)
         if err != nil {
-            return "", err
+            return "", "", err
         }
         if token != "" {
             _ = writeStoredToken(token)
-            return token, nil
+            return token, tokenSourceDeviceFlow, nil
         }
     }
 
-    return "", errors.New("no token found. Set GITHUB_TOKEN, or install GitHub CLI (gh) to login via web, or set GITHUB_OAUTH_CLIENT_ID to use device OAuth. See https://docs.github.com/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps for details.")
+    return "", "", errors.New("no token found. Set GITHUB_TOKEN, or install GitHub CLI (gh) to login via web, or set GITHUB_OAUTH_CLIENT_ID to use device OAuth. See https://docs.github.com/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps for details.")
 }
 
 func configTokenPath() (string, error) {
@@ -225,7 +976,7 @@ func tryGhToken() (string, error) {
     if _, err := exec.LookPath("gh"); err != nil {
         return "", err
     }
-    cmd := exec.Command("gh", "auth", "token")
+    cmd := exec.Command("gh", "auth", "token", "--hostname", githubHost())
     out, err := cmd.Output()
     if err != nil {
         return "", err
@@ -242,7 +993,7 @@ func tryGhWebLogin() error {
         return err
     }
     // Request repo scope to create repositories
-    cmd := exec.Command("gh", "auth", "login", "--web", "--scopes", "repo")
+    cmd := exec.Command("gh", "auth", "login", "--web", "--scopes", "repo", "--hostname", githubHost())
     cmd.Stdout = os.Stdout
     cmd.Stderr = os.Stderr
     cmd.Stdin = os.Stdin
@@ -267,22 +1018,32 @@ type deviceTokenResponse struct {
     ErrorDesc   string `json:"error_description"`
 }
 
+// Bounds applied to the device code lifetime reported by GitHub, so that a
+// missing or bogus expires_in neither fails instantly nor hangs forever.
+const (
+    minDeviceFlowWait     = 1 * time.Minute
+    maxDeviceFlowWait     = 30 * time.Minute
+    defaultDeviceFlowWait = 15 * time.Minute
+)
+
 // runDeviceFlow implements GitHub's OAuth Device Authorization Grant
 // Docs: https://docs.github.com/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#device-flow
-func runDeviceFlow(ctx context.Context, clientID string, scopes []string) (string, error) {
+// maxWait, if positive, caps how long to wait for the user to authorize.
+func runDeviceFlow(ctx context.Context, clientID string, scopes []string, maxWait time.Duration) (string, error) {
     // 1) Initiate device code
     values := url.Values{}
     values.Set("client_id", clientID)
     values.Set("scope", strings.Join(scopes, ","))
 
-    req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://github.com/login/device/code", strings.NewReader(values.Encode()))
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubServerURL+"/login/device/code", strings.NewReader(values.Encode()))
     if err != nil {
         return "", err
     }
     req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
     req.Header.Set("Accept", "application/json")
+    req.Header.Set("User-Agent", userAgent)
 
-    resp, err := http.DefaultClient.Do(req)
+    resp, err := httpClient.Do(req)
     if err != nil {
         return "", err
     }
@@ -313,7 +1074,7 @@ func runDeviceFlow(ctx context.Context, clientID string, scopes []string) (strin
     }
     ticker := time.NewTicker(pollInterval * time.Second)
     defer ticker.Stop()
-    timeout := time.After(time.Duration(dc.ExpiresIn) * time.Second)
+    timeout := time.After(deviceFlowWait(dc.ExpiresIn, maxWait))
 
     for {
         select {
@@ -336,20 +1097,39 @@ func runDeviceFlow(ctx context.Context, clientID string, scopes []string) (strin
     }
 }
 
+// deviceFlowWait returns how long to poll for a device token: GitHub's
+// expires_in clamped to a sane range, further capped by maxWait if positive.
+func deviceFlowWait(expiresIn int, maxWait time.Duration) time.Duration {
+    wait := time.Duration(expiresIn) * time.Second
+    switch {
+    case expiresIn <= 0:
+        wait = defaultDeviceFlowWait
+    case wait < minDeviceFlowWait:
+        wait = minDeviceFlowWait
+    case wait > maxDeviceFlowWait:
+        wait = maxDeviceFlowWait
+    }
+    if maxWait > 0 && maxWait < wait {
+        wait = maxWait
+    }
+    return wait
+}
+
 func pollDeviceToken(ctx context.Context, clientID, deviceCode string) (token string, continuePolling bool, err error) {
     values := url.Values{}
     values.Set("client_id", clientID)
     values.Set("device_code", deviceCode)
     values.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
 
-    req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://github.com/login/oauth/access_token", strings.NewReader(values.Encode()))
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubServerURL+"/login/oauth/access_token", strings.NewReader(values.Encode()))
     if err != nil {
         return "", true, err
     }
     req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
     req.Header.Set("Accept", "application/json")
+    req.Header.Set("User-Agent", userAgent)
 
-    resp, err := http.DefaultClient.Do(req)
+    resp, err := httpClient.Do(req)
     if err != nil {
         return "", true, err
     }
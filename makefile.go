@@ -0,0 +1,44 @@
+package main
+
+import (
+	"embed"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// makefileTemplates holds a Makefile template per -makefile ecosystem.
+//
+//go:embed templates/makefile/*.mk.tmpl
+var makefileTemplates embed.FS
+
+// Ecosystems accepted by -makefile.
+var makefileKinds = map[string]bool{"go": true, "node": true, "generic": true}
+
+// makefileData is what Makefile templates can refer to.
+type makefileData struct {
+	// Binary is the name of the program, derived from the repository name.
+	Binary string
+}
+
+var unsafeBinaryChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeMakefile writes a Makefile for kind ("go", "node" or "generic") to
+// path, naming the binary after repoName. It reports false and leaves the
+// file alone if path already exists.
+func writeMakefile(path, kind, repoName string) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	tmpl, err := template.ParseFS(makefileTemplates, "templates/makefile/"+kind+".mk.tmpl")
+	if err != nil {
+		return false, err
+	}
+	var b strings.Builder
+	data := makefileData{Binary: unsafeBinaryChars.ReplaceAllString(repoName, "-")}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return false, err
+	}
+	return true, writeScaffoldFile(path, b.String())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Values of -org-secret-visibility.
+const orgSecretVisibilitySelected = "selected"
+
+// getOrgSecrets fetches the Actions secrets called names from org, failing
+// for one that doesn't exist.
+func getOrgSecrets(ctx context.Context, client *github.Client, org string, names []string) ([]*github.Secret, error) {
+	secrets := make([]*github.Secret, 0, len(names))
+	for _, name := range names {
+		secret, resp, err := client.Actions.GetOrgSecret(ctx, org, name)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("organization %s has no Actions secret %s (or you lack admin access to its secrets)", org, name)
+			}
+			return nil, fmt.Errorf("failed to get organization secret %s: %w", name, explainSSO(err))
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// grantOrgSecret makes the org secret available to repo. Secrets visible to
+// selected repositories get repo added to their list; the others already
+// cover it unless they're limited to private repositories and repo is public.
+// With requireSelected, only secrets with selected visibility are accepted.
+// It reports whether anything changed.
+func grantOrgSecret(ctx context.Context, client *github.Client, org string, secret *github.Secret, repo *github.Repository, requireSelected bool) (bool, error) {
+	if secret.Visibility != orgSecretVisibilitySelected {
+		if requireSelected {
+			return false, fmt.Errorf("organization secret %s is visible to %s repositories, not selected ones (-org-secret-visibility selected)", secret.Name, secret.Visibility)
+		}
+		if secret.Visibility == "private" && !repo.GetPrivate() {
+			return false, fmt.Errorf("organization secret %s is only available to private repositories; change its visibility to selected repositories to share it with %s", secret.Name, repo.GetFullName())
+		}
+		return false, nil
+	}
+
+	listOpts := &github.ListOptions{PerPage: 100}
+	for {
+		selected, resp, err := client.Actions.ListSelectedReposForOrgSecret(ctx, org, secret.Name, listOpts)
+		if err != nil {
+			return false, fmt.Errorf("failed to list repositories of organization secret %s: %w", secret.Name, err)
+		}
+		for _, r := range selected.Repositories {
+			if r.GetID() == repo.GetID() {
+				return false, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	if _, err := client.Actions.AddSelectedRepoToOrgSecret(ctx, org, secret.Name, repo); err != nil {
+		return false, fmt.Errorf("failed to add %s to organization secret %s: %w", repo.GetFullName(), secret.Name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Color modes accepted by --color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorMode is set from --color before any output is written.
+var colorMode = colorAuto
+
+// colorEnabled reports whether output written to f should be colored. In
+// auto mode color is used only on terminals, and never when NO_COLOR is set,
+// on dumb terminals, or in CI.
+func colorEnabled(f *os.File) bool {
+	switch colorMode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+func paint(f *os.File, code, s string) string {
+	if !colorEnabled(f) {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// green colors s for standard output.
+func green(s string) string { return paint(os.Stdout, "32", s) }
+
+// successf prints a highlighted success message to standard output.
+func successf(format string, args ...any) {
+	fmt.Println(green(fmt.Sprintf(format, args...)))
+}
+
+// warnf logs a non-fatal problem to standard error.
+func warnf(format string, args ...any) {
+	log.Print(paint(os.Stderr, "33", "Warning:") + " " + fmt.Sprintf(format, args...))
+}
+
+// resultOutput receives the --json or --print result. In those modes
+// os.Stdout is pointed at stderr so that human-readable output, including
+// git's, stays out of it.
+var resultOutput *os.File
+
+// reserveStdout keeps stdout for the result and sends everything else to
+// stderr.
+func reserveStdout() {
+	resultOutput = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// runResult is the --json description of a completed run.
+type runResult struct {
+	Name     string       `json:"name"`
+	Owner    string       `json:"owner"`
+	URL      string       `json:"url"`
+	SSHURL   string       `json:"ssh_url"`
+	CloneURL string       `json:"clone_url"`
+	Created  bool         `json:"created"`
+	Branch   string       `json:"branch"`
+	Steps    []stepResult `json:"steps"`
+	// PagesURL is the live site with -wait-for-pages.
+	PagesURL string `json:"pages_url,omitempty"`
+	// RateLimit is only reported with -print-rate-limit.
+	RateLimit *rateLimitStatus `json:"rate_limit,omitempty"`
+	// Timings holds phase durations in milliseconds with -timings.
+	Timings map[string]int64 `json:"timings,omitempty"`
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(resultOutput)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printFields are the repository fields -print can output.
+var printFields = map[string]func(*github.Repository) string{
+	"ssh_url":   (*github.Repository).GetSSHURL,
+	"clone_url": (*github.Repository).GetCloneURL,
+	"html_url":  (*github.Repository).GetHTMLURL,
+	"full_name": (*github.Repository).GetFullName,
+}
+
+// printField writes the named field of repo on its own line.
+func printField(repo *github.Repository, field string) error {
+	_, err := fmt.Fprintln(resultOutput, printFields[field](repo))
+	return err
+}
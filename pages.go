@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// pagesURLTimeout bounds how long we wait for GitHub to report the URL of a
+// freshly enabled Pages site.
+const pagesURLTimeout = 30 * time.Second
+
+// enablePages publishes owner/repo with GitHub Pages from the root of branch.
+// If Pages is already enabled, the existing site is returned unchanged and
+// enabled is false.
+func enablePages(ctx context.Context, client *github.Client, owner, repo, branch string) (pages *github.Pages, enabled bool, err error) {
+	pages, _, err = client.Repositories.EnablePages(ctx, owner, repo, &github.Pages{
+		Source: &github.PagesSource{Branch: github.String(branch), Path: github.String("/")},
+	})
+	if err == nil {
+		return pages, true, nil
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusConflict {
+		pages, _, err = client.Repositories.GetPagesInfo(ctx, owner, repo)
+		if err == nil {
+			return pages, false, nil
+		}
+	}
+	if isPlanRestricted(err) {
+		return nil, false, fmt.Errorf("GitHub Pages is not available for %s/%s on the current plan (Pages on private repositories requires GitHub Pro, Team or Enterprise): %w", owner, repo, err)
+	}
+	return nil, false, fmt.Errorf("failed to enable GitHub Pages: %w", err)
+}
+
+// pagesURL returns the URL of the Pages site of owner/repo. GitHub may not
+// report it right after Pages is enabled, so it is polled for up to
+// pagesURLTimeout.
+func pagesURL(ctx context.Context, client *github.Client, owner, repo string, pages *github.Pages) (string, error) {
+	deadline := time.Now().Add(pagesURLTimeout)
+	for {
+		if pages != nil && pages.GetHTMLURL() != "" {
+			return pages.GetHTMLURL(), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("GitHub didn't report a Pages URL for %s/%s within %s", owner, repo, pagesURLTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+
+		var err error
+		pages, _, err = client.Repositories.GetPagesInfo(ctx, owner, repo)
+		if err != nil {
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+				return "", fmt.Errorf("GitHub Pages isn't enabled for %s/%s; add -pages to enable it", owner, repo)
+			}
+			return "", fmt.Errorf("failed to get GitHub Pages info: %w", err)
+		}
+	}
+}
+
+// pagesHTTPSTimeout bounds how long we wait for GitHub to issue the
+// certificate for a custom Pages domain before giving up on enforcing HTTPS.
+const pagesHTTPSTimeout = 2 * time.Minute
+
+// domainPattern matches domain names such as docs.example.com.
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// writeCNAME writes the CNAME file GitHub Pages reads the custom domain from
+// when publishing from a branch. An existing CNAME is left alone.
+func writeCNAME(path, domain string) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil {
+		if strings.TrimSpace(string(existing)) != domain {
+			warnf("%s names %s, not %s; leaving it as is", path, strings.TrimSpace(string(existing)), domain)
+		}
+		return false, nil
+	}
+	return true, writeScaffoldFile(path, domain)
+}
+
+// setPagesDomain configures domain as the custom domain of the Pages site of
+// owner/repo, then waits up to pagesHTTPSTimeout for GitHub to issue its
+// certificate and enforces HTTPS. Certificates can take longer than that
+// (DNS has to point at GitHub first), which is reported in the returned
+// description rather than as an error. A domain that's already set up with
+// HTTPS enforced is reported as an unchangedStep error.
+func setPagesDomain(ctx context.Context, client *github.Client, owner, repo, domain string) (string, error) {
+	current, _, err := client.Repositories.GetPagesInfo(ctx, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to get GitHub Pages info: %w", err)
+	}
+	if current.GetCNAME() == domain && current.GetHTTPSEnforced() {
+		return "", unchangedStep(domain + ", HTTPS enforced")
+	}
+
+	if _, err := client.Repositories.UpdatePages(ctx, owner, repo, &github.PagesUpdate{CNAME: github.String(domain)}); err != nil {
+		return "", fmt.Errorf("failed to set Pages domain: %w", err)
+	}
+
+	deadline := time.Now().Add(pagesHTTPSTimeout)
+	state := ""
+	for {
+		pages, _, err := client.Repositories.GetPagesInfo(ctx, owner, repo)
+		if err != nil {
+			return "", fmt.Errorf("failed to get GitHub Pages info: %w", err)
+		}
+		if pages.GetHTTPSEnforced() {
+			return domain + ", HTTPS enforced", nil
+		}
+		if pages.HTTPSCertificate != nil {
+			state = pages.HTTPSCertificate.GetState()
+		}
+		if state == "approved" {
+			break
+		}
+		if state == "errored" || state == "bad_authz" {
+			return "", fmt.Errorf("GitHub couldn't issue a certificate for %s (%s); check that its DNS points at GitHub Pages", domain, state)
+		}
+		if time.Now().After(deadline) {
+			if state == "" {
+				state = "not requested yet"
+			}
+			return fmt.Sprintf("%s; HTTPS not enforced, the certificate is %s (enforce it in the repository's Pages settings once it's issued)", domain, state), nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	update := &github.PagesUpdate{CNAME: github.String(domain), HTTPSEnforced: github.Bool(true)}
+	if _, err := client.Repositories.UpdatePages(ctx, owner, repo, update); err != nil {
+		return "", fmt.Errorf("failed to enforce HTTPS: %w", err)
+	}
+	return domain + ", HTTPS enforced", nil
+}
+
+// pagesBuildStep is the name of the post-create step that waits for the
+// Pages build; its detail is the live URL.
+const pagesBuildStep = "pages build"
+
+// waitForPagesBuild polls the latest Pages build of owner/repo until it has
+// been built, then returns the URL of the live site. A failed build is
+// reported with GitHub's error message. GitHub may take a moment to even
+// start the first build, so a missing build is waited for too.
+func waitForPagesBuild(ctx context.Context, client *github.Client, owner, repo string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	status := "not started"
+	for {
+		build, _, err := client.Repositories.GetLatestPagesBuild(ctx, owner, repo)
+		var errResp *github.ErrorResponse
+		switch {
+		case err == nil:
+			status = build.GetStatus()
+		case errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound:
+		default:
+			return "", fmt.Errorf("failed to get Pages build: %w", err)
+		}
+
+		switch status {
+		case "built":
+			pages, _, err := client.Repositories.GetPagesInfo(ctx, owner, repo)
+			if err != nil {
+				return "", fmt.Errorf("failed to get GitHub Pages info: %w", err)
+			}
+			return pages.GetHTMLURL(), nil
+		case "errored":
+			return "", fmt.Errorf("the Pages build failed: %s", build.GetError().GetMessage())
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("the Pages build wasn't done within %s (status: %s)", timeout, status)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// setHomepage sets the homepage URL shown on the repository page.
+func setHomepage(ctx context.Context, client *github.Client, owner, repo, url string) error {
+	if _, _, err := client.Repositories.Edit(ctx, owner, repo, &github.Repository{Homepage: github.String(url)}); err != nil {
+		return fmt.Errorf("failed to set homepage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// dryRunPlan is the --dry-run --json description of everything a run would
+// do. Its fields are documented in the README.
+type dryRunPlan struct {
+	Repository    planRepository `json:"repository"`
+	RemoteURL     string         `json:"remote_url"`
+	Branch        string         `json:"branch"`
+	WorkingBranch string         `json:"working_branch,omitempty"`
+	Since         string         `json:"since,omitempty"`
+	Files         []string       `json:"files"`
+	Steps         []string       `json:"steps"`
+}
+
+// planRepository is the repository a run would create.
+type planRepository struct {
+	Name        string   `json:"name"`
+	Owner       string   `json:"owner"`
+	Visibility  string   `json:"visibility"`
+	Description string   `json:"description"`
+	Topics      []string `json:"topics"`
+	// DetectedTopics are the topics -auto-topics found, before merging
+	// them into Topics.
+	DetectedTopics []string `json:"detected_topics,omitempty"`
+}
+
+// buildPlan describes a run creating owner/name with visibility and staging
+// paths, without changing anything.
+func buildPlan(ctx context.Context, client *github.Client, opts *options, owner, org, name, visibility string, paths []string) (*dryRunPlan, error) {
+	preview, err := previewCommit(opts, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := opts.topics
+	var detected []string
+	if opts.autoTopics {
+		detected = detectTopics()
+		topics = mergeTopics(topics, detected)
+	}
+
+	plan := &dryRunPlan{
+		Repository: planRepository{
+			Name:           name,
+			Owner:          owner,
+			Visibility:     visibility,
+			Description:    opts.description,
+			Topics:         append([]string{}, topics...),
+			DetectedTopics: detected,
+		},
+		RemoteURL: fmt.Sprintf("git@%s:%s/%s.git", githubHost(), owner, name),
+		Since:     opts.since,
+		Files:     []string{},
+		Steps:     []string{},
+	}
+	plan.Branch, plan.WorkingBranch = plannedBranches(opts)
+	for _, change := range preview.Changes {
+		_, path, _ := strings.Cut(change, "\t")
+		plan.Files = append(plan.Files, path)
+	}
+
+	repo := &github.Repository{Name: github.String(name), Owner: &github.User{Login: github.String(owner)}}
+	for _, s := range postCreateSteps(ctx, client, opts, repo, org, plan.Branch, true, !opts.noPush) {
+		plan.Steps = append(plan.Steps, s.name)
+	}
+	return plan, nil
+}
+
+// plannedBranch returns the branch that would be pushed: the current one, or
+// the one git init would create.
+func plannedBranch() string {
+	if !isInitialized() {
+		return initDefaultBranch()
+	}
+	if branch, err := gitOutput("symbolic-ref", "--short", "HEAD"); err == nil {
+		return branch
+	}
+	return initDefaultBranch()
+}
+
+// plannedBranches returns the branch that would be pushed and, with
+// -branch and -keep-default-branch, the working branch pushed along with it.
+// Like the run, -branch only takes over from the default branch.
+func plannedBranches(opts *options) (branch, working string) {
+	branch = plannedBranch()
+	if opts.branch == "" || branch == opts.branch || branch != initDefaultBranch() {
+		return branch, ""
+	}
+	if opts.keepDefaultBranch {
+		return branch, opts.branch
+	}
+	return opts.branch, ""
+}
+
+// initDefaultBranch returns the name of the branch git init creates.
+func initDefaultBranch() string {
+	if branch, err := gitOutput("config", "init.defaultBranch"); err == nil && branch != "" {
+		return branch
+	}
+	return "master"
+}
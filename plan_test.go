@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// chdirTemp changes into a new temporary directory for the rest of the test.
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func TestBuildPlanListsScaffoldedFiles(t *testing.T) {
+	chdirTemp(t)
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseFlags([]string{"-license", "mit", "-gitignore-template", "Go", "-dry-run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := stagePaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := buildPlan(context.Background(), github.NewClient(nil), opts, "octocat", "", "project", visibilityPublic, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"main.go", ".gitignore", "LICENSE"}
+	if !slices.Equal(plan.Files, want) {
+		t.Errorf("plan files = %q, want %q", plan.Files, want)
+	}
+	if _, err := os.Stat(".git"); err == nil {
+		t.Error("building the plan created a repository")
+	}
+}
+
+func TestBuildPlanSkipsExistingLicense(t *testing.T) {
+	chdirTemp(t)
+	for _, name := range []string{"main.go", "LICENSE", ".gitignore"} {
+		if err := os.WriteFile(name, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts, err := parseFlags([]string{"-license", "mit", "-gitignore-template", "Go", "-dry-run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := stagePaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := buildPlan(context.Background(), github.NewClient(nil), opts, "octocat", "", "project", visibilityPublic, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// LICENSE is committed as it is; .gitignore gets the template merged in
+	want := []string{"LICENSE", "main.go", ".gitignore"}
+	if !slices.Equal(plan.Files, want) {
+		t.Errorf("plan files = %q, want %q", plan.Files, want)
+	}
+}
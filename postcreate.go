@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// postCreateSteps returns the steps configured by opts to run once repo has
+// been created (or reused) and pushed. org is the organization owning repo,
+// if any, branch is the branch that was pushed, and created reports whether
+// this run created it. Without a push (pushed is false) the steps that need
+// the branch on GitHub are skipped; the others run as usual.
+func postCreateSteps(ctx context.Context, client *github.Client, opts *options, repo *github.Repository, org, branch string, created, pushed bool) []step {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	var steps []step
+	// needsBranch marks steps that act on the pushed branch
+	needsBranch := map[string]bool{}
+
+	if opts.noDefaultLabels {
+		steps = append(steps, step{name: "delete default labels", required: true, run: func() (string, error) {
+			if !created {
+				return "", skipStep("not deleting labels from an existing repository")
+			}
+			deleted, err := deleteDefaultLabels(ctx, client, owner, name)
+			if err != nil {
+				return "", err
+			}
+			if len(deleted) == 0 {
+				return "", unchangedStep("no default labels left")
+			}
+			return strings.Join(deleted, ", "), nil
+		}})
+	}
+
+	if len(opts.labels) > 0 {
+		steps = append(steps, step{name: "labels", required: true, run: func() (string, error) {
+			return applyLabels(ctx, client, owner, name, opts.labels, opts.labelsSync)
+		}})
+	}
+
+	if len(opts.topics) > 0 || opts.autoTopics {
+		steps = append(steps, step{name: "topics", required: true, run: func() (string, error) {
+			topics := opts.topics
+			if opts.autoTopics {
+				// What was detected is shown on its own; the step reports
+				// the topics applied after merging
+				detected := detectTopics()
+				if len(detected) > 0 {
+					fmt.Printf("Detected topics: %s\n", strings.Join(detected, ", "))
+				}
+				topics = mergeTopics(topics, detected)
+			}
+			if len(topics) == 0 {
+				return "", skipStep("no topics detected")
+			}
+			if opts.topicsReplace {
+				changed, err := replaceTopics(ctx, client, owner, name, topics)
+				if err != nil {
+					return "", err
+				}
+				if !changed {
+					return "", unchangedStep(strings.Join(topics, ", "))
+				}
+				return strings.Join(topics, ", "), nil
+			}
+			merged, changed, err := addTopics(ctx, client, owner, name, topics)
+			if err != nil {
+				return "", err
+			}
+			if !changed {
+				return "", unchangedStep(strings.Join(merged, ", "))
+			}
+			return strings.Join(merged, ", "), nil
+		}})
+	}
+
+	if len(opts.orgSecrets) > 0 {
+		steps = append(steps, step{name: "organization secrets", required: true, run: func() (string, error) {
+			if org == "" {
+				return "", skipStep("-org-secret only applies to organization repositories")
+			}
+			secrets, err := getOrgSecrets(ctx, client, org, opts.orgSecrets)
+			if err != nil {
+				return "", err
+			}
+			changed := false
+			for _, secret := range secrets {
+				added, err := grantOrgSecret(ctx, client, org, secret, repo, opts.orgSecretVisibility == orgSecretVisibilitySelected)
+				if err != nil {
+					return "", err
+				}
+				changed = changed || added
+			}
+			detail := strings.Join(opts.orgSecrets, ", ")
+			if !changed {
+				return "", unchangedStep(detail)
+			}
+			return detail, nil
+		}})
+	}
+
+	// Languages are only known once GitHub has the code
+	if opts.autoLanguageTopics {
+		needsBranch["language topics"] = true
+		steps = append(steps, step{name: "language topics", required: true, run: func() (string, error) {
+			topics, err := languageTopics(ctx, client, owner, name)
+			if err != nil {
+				return "", err
+			}
+			if len(topics) == 0 {
+				return "", skipStep("GitHub didn't detect any languages")
+			}
+			merged, changed, err := addTopics(ctx, client, owner, name, topics)
+			if err != nil {
+				return "", err
+			}
+			if !changed {
+				return "", unchangedStep(strings.Join(merged, ", "))
+			}
+			return strings.Join(topics, ", "), nil
+		}})
+	}
+
+	if opts.teamMaintain != "" {
+		steps = append(steps, step{name: "team access", required: true, run: func() (string, error) {
+			if org == "" {
+				return "", skipStep("-team-maintain only applies to organization repositories")
+			}
+			user, _, err := client.Users.Get(ctx, "")
+			if err != nil {
+				return "", fmt.Errorf("failed to get user: %w", err)
+			}
+			return grantTeamMaintain(ctx, client, org, opts.teamMaintain, repo, user.GetLogin())
+		}})
+	}
+
+	if opts.remoteHead != "" {
+		needsBranch["default branch"] = true
+		steps = append(steps, step{name: "default branch", required: true, run: func() (string, error) {
+			if repo.GetDefaultBranch() == opts.remoteHead {
+				return "", unchangedStep(opts.remoteHead)
+			}
+			if _, _, err := client.Repositories.Edit(ctx, owner, name, &github.Repository{DefaultBranch: github.String(opts.remoteHead)}); err != nil {
+				return "", fmt.Errorf("failed to make %s the default branch (it has to exist on GitHub): %w", opts.remoteHead, err)
+			}
+			return opts.remoteHead, nil
+		}})
+	}
+
+	// The homepage step needs the site enabled by the pages step, so the
+	// pages step runs first and hands its result over.
+	var pages *github.Pages
+	if opts.pages {
+		needsBranch["pages"] = true
+		steps = append(steps, step{name: "pages", required: true, run: func() (string, error) {
+			var enabled bool
+			var err error
+			if pages, enabled, err = enablePages(ctx, client, owner, name, branch); err != nil {
+				return "", err
+			}
+			if !enabled {
+				return "", unchangedStep("already published from " + pages.GetSource().GetBranch())
+			}
+			return "published from " + branch, nil
+		}})
+	}
+
+	if opts.pagesDomain != "" {
+		steps = append(steps, step{name: "pages domain", required: true, run: func() (string, error) {
+			if pages == nil {
+				return "", skipStep("GitHub Pages wasn't enabled")
+			}
+			return setPagesDomain(ctx, client, owner, name, opts.pagesDomain)
+		}})
+	}
+
+	if opts.waitForPages {
+		steps = append(steps, step{name: pagesBuildStep, required: true, run: func() (string, error) {
+			if pages == nil {
+				return "", skipStep("GitHub Pages wasn't enabled")
+			}
+			return waitForPagesBuild(ctx, client, owner, name, opts.pagesTimeout)
+		}})
+	}
+
+	if opts.homepageFromPages {
+		steps = append(steps, step{name: "homepage", required: true, run: func() (string, error) {
+			if opts.pages && pages == nil {
+				return "", skipStep("GitHub Pages wasn't enabled")
+			}
+			url, err := pagesURL(ctx, client, owner, name, pages)
+			if err != nil {
+				return "", err
+			}
+			if repo.GetHomepage() == url {
+				return "", unchangedStep(url)
+			}
+			if err := setHomepage(ctx, client, owner, name, url); err != nil {
+				return "", err
+			}
+			return url, nil
+		}})
+	}
+
+	if opts.protectionFrom != "" {
+		steps = append(steps, step{name: "branch protection", required: true, run: func() (string, error) {
+			created, err := copyProtection(ctx, client, opts.protectionFrom, owner, name)
+			if err != nil {
+				return "", err
+			}
+			if !created {
+				return "", unchangedStep("ruleset copied from " + opts.protectionFrom + " already exists")
+			}
+			return "copied from " + opts.protectionFrom, nil
+		}})
+	}
+
+	if opts.protectDefault {
+		needsBranch["default branch protection"] = true
+		steps = append(steps, step{name: "default branch protection", required: true, run: func() (string, error) {
+			codeOwners := opts.defaultOwner != ""
+			changed, err := protectBranch(ctx, client, owner, name, branch, codeOwners)
+			if err != nil {
+				return "", err
+			}
+			detail := branch + ": pull requests need an approving review"
+			if codeOwners {
+				detail = branch + ": pull requests need a review from " + opts.defaultOwner
+			}
+			if !changed {
+				return "", unchangedStep(detail)
+			}
+			return detail, nil
+		}})
+	}
+
+	if opts.secretScanning {
+		steps = append(steps, step{name: "secret scanning", required: true, run: func() (string, error) {
+			return enableSecretScanning(ctx, client, owner, name, opts.pushProtection)
+		}})
+	}
+
+	if opts.protectTags != "" {
+		steps = append(steps, step{name: "tag protection", required: true, run: func() (string, error) {
+			created, err := protectTags(ctx, client, owner, name, opts.protectTags)
+			if err != nil {
+				return "", err
+			}
+			if !created {
+				return "", unchangedStep("tags matching " + opts.protectTags + " already protected")
+			}
+			return "tags matching " + opts.protectTags, nil
+		}})
+	}
+
+	if !pushed {
+		for i := range steps {
+			if needsBranch[steps[i].name] {
+				steps[i].run = func() (string, error) {
+					return "", skipStep(fmt.Sprintf("%s wasn't pushed; run repoinit again after pushing", branch))
+				}
+			}
+		}
+	}
+
+	// Established repositories are only pushed to, not reconfigured
+	if !created && opts.noEditExisting {
+		for i := range steps {
+			steps[i].run = func() (string, error) {
+				return "", skipStep("existing repository left unchanged (-no-edit-existing)")
+			}
+		}
+	}
+
+	return steps
+}
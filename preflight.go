@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Exit codes for --check-name.
+const (
+	exitNameAvailable = 0
+	exitNameTaken     = 3
+)
+
+// checkNameAvailable reports whether owner/name does not exist yet.
+func checkNameAvailable(ctx context.Context, client *github.Client, owner, name string) (bool, error) {
+	_, _, err := client.Repositories.Get(ctx, owner, name)
+	if err == nil {
+		return false, nil
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+// enclosingWorktree returns the top-level directory of the git work tree
+// the current directory is in, if that is some parent directory rather than
+// the current directory itself. Plain git commands would then act on that
+// repository.
+func enclosingWorktree() (string, error) {
+	if inside, err := gitOutput("rev-parse", "--is-inside-work-tree"); err != nil || inside != "true" {
+		return "", nil
+	}
+	top, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	// Compare resolved paths; either may go through a symlink
+	resolvedTop, err := filepath.EvalSymlinks(top)
+	if err != nil {
+		return "", err
+	}
+	resolvedPwd, err := filepath.EvalSymlinks(pwd)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Clean(resolvedTop) == filepath.Clean(resolvedPwd) {
+		return "", nil
+	}
+	return top, nil
+}
+
+// Repository visibilities accepted by -visibility.
+const (
+	visibilityPublic   = "public"
+	visibilityPrivate  = "private"
+	visibilityInternal = "internal"
+)
+
+// orgInternalDefault reports whether organization repositories should be
+// internal unless -visibility says otherwise. The policy comes from
+// -org-create-as-internal-default or REPOINIT_ORG_CREATE_AS_INTERNAL_DEFAULT,
+// which can be set once in .env.
+func orgInternalDefault(flagValue bool) bool {
+	if flagValue {
+		return true
+	}
+	v, _ := strconv.ParseBool(os.Getenv("REPOINIT_ORG_CREATE_AS_INTERNAL_DEFAULT"))
+	return v
+}
+
+// checkOrgAllowsVisibility fails early if org's member privileges don't let
+// the authenticated user create a repository with the given visibility
+// ("public", "private" or "internal"), instead of letting Create fail with a
+// bare 403. Organization owners are not subject to these restrictions.
+func checkOrgAllowsVisibility(ctx context.Context, client *github.Client, org, visibility string) error {
+	o, _, err := client.Organizations.Get(ctx, org)
+	if err != nil {
+		return fmt.Errorf("failed to get organization %s: %w", org, explainSSO(err))
+	}
+
+	var allowed *bool
+	switch visibility {
+	case "public":
+		allowed = o.MembersCanCreatePublicRepos
+	case "private":
+		allowed = o.MembersCanCreatePrivateRepos
+	case "internal":
+		allowed = o.MembersCanCreateInternalRepos
+	}
+	// The privilege fields are only visible to members; if they're missing
+	// there's nothing to check against and Create will report any problem.
+	if allowed == nil || *allowed {
+		return nil
+	}
+
+	membership, _, err := client.Organizations.GetOrgMembership(ctx, "", org)
+	if err == nil && membership.GetRole() == "admin" {
+		return nil
+	}
+	return fmt.Errorf("organization %s does not allow members to create %s repositories; ask an organization owner to create it or to change the member privileges", org, visibility)
+}
+
+// inheritOrgDefaults applies org's defaults for new repositories to opts
+// where no flag was given, and returns a description of each. GitHub only
+// exposes some of them: which visibilities members may create, and whether
+// secret scanning and push protection are on for new repositories. The
+// default branch name isn't available through the API.
+func inheritOrgDefaults(ctx context.Context, client *github.Client, org string, opts *options) ([]string, error) {
+	o, _, err := client.Organizations.Get(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization %s: %w", org, explainSSO(err))
+	}
+
+	var inherited []string
+	// Members who can't create public repositories get the first
+	// visibility they can
+	if !opts.given["visibility"] && !o.GetMembersCanCreatePublicRepos() && o.MembersCanCreatePublicRepos != nil {
+		switch {
+		case o.GetMembersCanCreatePrivateRepos():
+			opts.visibility = visibilityPrivate
+		case o.GetMembersCanCreateInternalRepos():
+			opts.visibility = visibilityInternal
+		}
+		if opts.visibility != "" {
+			inherited = append(inherited, "visibility "+opts.visibility)
+		}
+	}
+	if !opts.given["secret-scanning"] && o.GetSecretScanningEnabledForNewRepos() {
+		opts.secretScanning = true
+		inherited = append(inherited, "secret scanning")
+	}
+	if !opts.given["push-protection"] && o.GetSecretScanningPushProtectionEnabledForNewRepos() {
+		opts.secretScanning = true
+		opts.pushProtection = true
+		inherited = append(inherited, "push protection")
+	}
+	return inherited, nil
+}
+
+// resolveOwner determines where a repository for owner is created. It returns
+// the organization to pass to Create, or "" if owner is the authenticated
+// user. Other users' accounts are rejected since repositories can't be
+// created on their behalf.
+func resolveOwner(ctx context.Context, client *github.Client, owner string) (org string, err error) {
+	account, _, err := client.Users.Get(ctx, owner)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up owner %s: %w", owner, err)
+	}
+	if account.GetType() == "Organization" {
+		return account.GetLogin(), nil
+	}
+
+	self, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	if !strings.EqualFold(self.GetLogin(), account.GetLogin()) {
+		return "", fmt.Errorf("%s is another user's account; repositories can only be created for yourself (%s) or an organization", account.GetLogin(), self.GetLogin())
+	}
+	return "", nil
+}
+
+// branchExists reports whether owner/repo has a branch named branch.
+func branchExists(ctx context.Context, client *github.Client, owner, repo, branch string) (bool, error) {
+	_, _, err := client.Repositories.GetBranch(ctx, owner, repo, branch, 0)
+	if err == nil {
+		return true, nil
+	}
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
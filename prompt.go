@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// isInteractive reports whether the user can be prompted, i.e. both stdin
+// and stdout are terminals.
+func isInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prompt prints question and returns the trimmed line the user enters.
+func prompt(question string) (string, error) {
+	fmt.Print(question)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// isNameTaken reports whether err is GitHub refusing to create a repository
+// because the name is already in use. Other validation errors, such as a
+// description that is too long, come with the same status code.
+func isNameTaken(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	for _, e := range errResp.Errors {
+		if e.Field == "name" && (e.Code == "already_exists" || strings.Contains(e.Message, "already exists")) {
+			return true
+		}
+	}
+	return false
+}
+
+// promptNewRepoName tells the user owner/name is taken and asks for another
+// name, offering suggestions that are still free.
+func promptNewRepoName(ctx context.Context, client *github.Client, owner, name string) (string, error) {
+	var suggestions []string
+	for _, candidate := range repoNameSuggestions(owner, name) {
+		if available, err := checkNameAvailable(ctx, client, owner, candidate); err == nil && available {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+
+	fmt.Printf("Repository %s/%s already exists.\n", owner, name)
+	for i, s := range suggestions {
+		fmt.Printf("  %d) %s\n", i+1, s)
+	}
+
+	for {
+		answer, err := prompt("Pick a number or enter a new name (empty to abort): ")
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			return "", errors.New("aborted")
+		}
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(suggestions) {
+				fmt.Println("No such suggestion.")
+				continue
+			}
+			return suggestions[n-1], nil
+		}
+		return answer, nil
+	}
+}
+
+// repoNameSuggestions returns alternative names to offer when name is taken.
+func repoNameSuggestions(owner, name string) []string {
+	suggestions := []string{name + "-2"}
+	if _, err := os.Stat("go.mod"); err == nil && !strings.HasSuffix(name, "-go") {
+		suggestions = append(suggestions, name+"-go")
+	}
+	if owner != "" && !strings.HasSuffix(name, "-"+owner) {
+		suggestions = append(suggestions, name+"-"+owner)
+	}
+	return suggestions
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// protectTags creates a tag ruleset on owner/repo that prevents tags matching
+// pattern from being deleted, moved or force-pushed. It reports whether the
+// ruleset was created; one by the same name, from an earlier run, is kept.
+func protectTags(ctx context.Context, client *github.Client, owner, repo, pattern string) (bool, error) {
+	ruleset := &github.Ruleset{
+		Name:        fmt.Sprintf("Protect tags %s", pattern),
+		Target:      github.String("tag"),
+		Enforcement: "active",
+		Conditions: &github.RulesetConditions{
+			RefName: &github.RulesetRefConditionParameters{
+				Include: []string{"refs/tags/" + pattern},
+				Exclude: []string{},
+			},
+		},
+		Rules: []*github.RepositoryRule{
+			github.NewDeletionRule(),
+			github.NewUpdateRule(nil),
+			github.NewNonFastForwardRule(),
+		},
+	}
+	if exists, err := rulesetExists(ctx, client, owner, repo, ruleset.Name); err != nil || exists {
+		return false, err
+	}
+
+	_, _, err := client.Repositories.CreateRuleset(ctx, owner, repo, ruleset)
+	if err != nil {
+		if isPlanRestricted(err) {
+			return false, fmt.Errorf("tag protection is not available for %s/%s on the current plan (rulesets on private repositories require GitHub Pro, Team or Enterprise): %w", owner, repo, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// rulesetExists reports whether owner/repo has a ruleset called name.
+func rulesetExists(ctx context.Context, client *github.Client, owner, repo, name string) (bool, error) {
+	rulesets, resp, err := client.Repositories.GetAllRulesets(ctx, owner, repo, false)
+	if err != nil {
+		// Without rulesets support there is nothing to find; creating one
+		// reports the plan restriction
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to list rulesets: %w", err)
+	}
+	for _, r := range rulesets {
+		if r.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// repositoryAdminRoleID is the actor id of the built-in repository admin role
+// in ruleset bypass lists.
+const repositoryAdminRoleID = 5
+
+// protectBranch protects branch of owner/repo so changes have to go through
+// a pull request with an approving review; with codeOwnerReviews one of the
+// code owners has to approve. It reports whether the protection changed;
+// a branch that already requires such reviews is left alone. Existing
+// protection is kept and only has its review requirements raised, since
+// updating replaces the protection as a whole.
+func protectBranch(ctx context.Context, client *github.Client, owner, repo, branch string, codeOwnerReviews bool) (bool, error) {
+	req := &github.ProtectionRequest{}
+	current, resp, err := client.Repositories.GetBranchProtection(ctx, owner, repo, branch)
+	switch {
+	case err == nil:
+		if reviews := current.RequiredPullRequestReviews; reviews != nil && reviews.RequiredApprovingReviewCount >= 1 && (reviews.RequireCodeOwnerReviews || !codeOwnerReviews) {
+			return false, nil
+		}
+		req = protectionRequest(current)
+	case errors.Is(err, github.ErrBranchNotProtected), resp != nil && resp.StatusCode == http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("failed to get protection of %s: %w", branch, err)
+	}
+
+	if req.RequiredPullRequestReviews == nil {
+		req.RequiredPullRequestReviews = &github.PullRequestReviewsEnforcementRequest{}
+	}
+	reviews := req.RequiredPullRequestReviews
+	reviews.RequiredApprovingReviewCount = max(reviews.RequiredApprovingReviewCount, 1)
+	reviews.RequireCodeOwnerReviews = reviews.RequireCodeOwnerReviews || codeOwnerReviews
+	if _, _, err := client.Repositories.UpdateBranchProtection(ctx, owner, repo, branch, req); err != nil {
+		if isPlanRestricted(err) {
+			return false, fmt.Errorf("branch protection is not available for %s/%s on the current plan (protecting private repositories requires GitHub Pro, Team or Enterprise): %w", owner, repo, err)
+		}
+		return false, fmt.Errorf("failed to protect %s: %w", branch, err)
+	}
+	return true, nil
+}
+
+// protectionRequest returns the request that sets branch protection p again
+// as it is.
+func protectionRequest(p *github.Protection) *github.ProtectionRequest {
+	req := &github.ProtectionRequest{
+		EnforceAdmins: p.EnforceAdmins != nil && p.EnforceAdmins.Enabled,
+	}
+	if p.BlockCreations != nil {
+		req.BlockCreations = p.BlockCreations.Enabled
+	}
+	if p.LockBranch != nil {
+		req.LockBranch = p.LockBranch.Enabled
+	}
+	if p.AllowForkSyncing != nil {
+		req.AllowForkSyncing = p.AllowForkSyncing.Enabled
+	}
+	if p.RequireLinearHistory != nil {
+		req.RequireLinearHistory = github.Bool(p.RequireLinearHistory.Enabled)
+	}
+	if p.AllowForcePushes != nil {
+		req.AllowForcePushes = github.Bool(p.AllowForcePushes.Enabled)
+	}
+	if p.AllowDeletions != nil {
+		req.AllowDeletions = github.Bool(p.AllowDeletions.Enabled)
+	}
+	if p.RequiredConversationResolution != nil {
+		req.RequiredConversationResolution = github.Bool(p.RequiredConversationResolution.Enabled)
+	}
+
+	// Only one of checks and contexts may be given; checks also carry the
+	// app a check has to come from
+	if checks := p.RequiredStatusChecks; checks != nil {
+		req.RequiredStatusChecks = &github.RequiredStatusChecks{Strict: checks.Strict, Checks: checks.Checks}
+		if len(checks.Checks) == 0 {
+			req.RequiredStatusChecks.Contexts = checks.Contexts
+		}
+	}
+
+	if r := p.RequiredPullRequestReviews; r != nil {
+		reviews := &github.PullRequestReviewsEnforcementRequest{
+			DismissStaleReviews:          r.DismissStaleReviews,
+			RequireCodeOwnerReviews:      r.RequireCodeOwnerReviews,
+			RequiredApprovingReviewCount: r.RequiredApprovingReviewCount,
+			RequireLastPushApproval:      github.Bool(r.RequireLastPushApproval),
+		}
+		if d := r.DismissalRestrictions; d != nil && hasActors(d.Users, d.Teams, d.Apps) {
+			users, teams, apps := actorNames(d.Users, d.Teams, d.Apps)
+			reviews.DismissalRestrictionsRequest = &github.DismissalRestrictionsRequest{Users: &users, Teams: &teams, Apps: &apps}
+		}
+		if b := r.BypassPullRequestAllowances; b != nil && hasActors(b.Users, b.Teams, b.Apps) {
+			users, teams, apps := actorNames(b.Users, b.Teams, b.Apps)
+			reviews.BypassPullRequestAllowancesRequest = &github.BypassPullRequestAllowancesRequest{Users: users, Teams: teams, Apps: apps}
+		}
+		req.RequiredPullRequestReviews = reviews
+	}
+
+	if r := p.Restrictions; r != nil {
+		users, teams, apps := actorNames(r.Users, r.Teams, r.Apps)
+		req.Restrictions = &github.BranchRestrictionsRequest{Users: users, Teams: teams, Apps: apps}
+	}
+	return req
+}
+
+// actorNames returns the user logins, team slugs and app slugs that
+// protection requests identify actors by.
+func actorNames(users []*github.User, teams []*github.Team, apps []*github.App) (userNames, teamSlugs, appSlugs []string) {
+	userNames, teamSlugs, appSlugs = []string{}, []string{}, []string{}
+	for _, u := range users {
+		userNames = append(userNames, u.GetLogin())
+	}
+	for _, t := range teams {
+		teamSlugs = append(teamSlugs, t.GetSlug())
+	}
+	for _, a := range apps {
+		appSlugs = append(appSlugs, a.GetSlug())
+	}
+	return userNames, teamSlugs, appSlugs
+}
+
+// copyProtection reads the branch protection of the default branch of source
+// ("owner/repo") and applies it to the default branch of owner/repo as a
+// ruleset. Settings that can't be carried over are reported as warnings. It
+// reports whether the ruleset was created; one copied by an earlier run is
+// kept.
+func copyProtection(ctx context.Context, client *github.Client, source, owner, repo string) (bool, error) {
+	if exists, err := rulesetExists(ctx, client, owner, repo, copiedRulesetName(source)); err != nil || exists {
+		return false, err
+	}
+
+	srcOwner, srcName, _ := strings.Cut(source, "/")
+	src, _, err := client.Repositories.Get(ctx, srcOwner, srcName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s: %w", source, err)
+	}
+	protection, _, err := client.Repositories.GetBranchProtection(ctx, srcOwner, srcName, src.GetDefaultBranch())
+	if err != nil {
+		return false, fmt.Errorf("failed to get branch protection of %s:%s: %w", source, src.GetDefaultBranch(), err)
+	}
+
+	ruleset, warnings := protectionRuleset(protection, source)
+	for _, w := range warnings {
+		warnf("%s", w)
+	}
+	if len(ruleset.Rules) == 0 {
+		return false, fmt.Errorf("the protection of %s has no rules that can be copied", source)
+	}
+
+	if _, _, err := client.Repositories.CreateRuleset(ctx, owner, repo, ruleset); err != nil {
+		if isPlanRestricted(err) {
+			return false, fmt.Errorf("branch rulesets are not available for %s/%s on the current plan (rulesets on private repositories require GitHub Pro, Team or Enterprise): %w", owner, repo, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// copiedRulesetName is the name of the ruleset copied from source.
+func copiedRulesetName(source string) string { return "Protection copied from " + source }
+
+// protectionRuleset translates classic branch protection into a ruleset for
+// the default branch. It returns warnings for settings rulesets can't express
+// or that may not apply to another repository.
+func protectionRuleset(p *github.Protection, source string) (*github.Ruleset, []string) {
+	ruleset := &github.Ruleset{
+		Name:        copiedRulesetName(source),
+		Target:      github.String("branch"),
+		Enforcement: "active",
+		Conditions: &github.RulesetConditions{
+			RefName: &github.RulesetRefConditionParameters{
+				Include: []string{"~DEFAULT_BRANCH"},
+				Exclude: []string{},
+			},
+		},
+	}
+	var warnings []string
+
+	if reviews := p.RequiredPullRequestReviews; reviews != nil || p.GetRequiredConversationResolution().Enabled {
+		params := &github.PullRequestRuleParameters{
+			RequiredReviewThreadResolution: p.GetRequiredConversationResolution().Enabled,
+		}
+		if reviews != nil {
+			params.DismissStaleReviewsOnPush = reviews.DismissStaleReviews
+			params.RequireCodeOwnerReview = reviews.RequireCodeOwnerReviews
+			params.RequiredApprovingReviewCount = reviews.RequiredApprovingReviewCount
+			params.RequireLastPushApproval = reviews.RequireLastPushApproval
+			if d := reviews.DismissalRestrictions; d != nil && hasActors(d.Users, d.Teams, d.Apps) {
+				warnings = append(warnings, "review dismissal restrictions were not copied; rulesets don't support them")
+			}
+			if b := reviews.BypassPullRequestAllowances; b != nil && hasActors(b.Users, b.Teams, b.Apps) {
+				warnings = append(warnings, "pull request bypass allowances were not copied; add bypass actors to the ruleset by hand")
+			}
+		}
+		ruleset.Rules = append(ruleset.Rules, github.NewPullRequestRule(params))
+	}
+
+	if checks := p.RequiredStatusChecks; checks != nil {
+		params := &github.RequiredStatusChecksRuleParameters{StrictRequiredStatusChecksPolicy: checks.Strict}
+		for _, check := range checks.Checks {
+			rule := github.RuleRequiredStatusChecks{Context: check.Context}
+			if check.GetAppID() > 0 {
+				rule.IntegrationID = check.AppID
+				warnings = append(warnings, fmt.Sprintf("status check %q must come from GitHub App %d, which has to be installed on the new repository", check.Context, check.GetAppID()))
+			}
+			params.RequiredStatusChecks = append(params.RequiredStatusChecks, rule)
+		}
+		if len(checks.Checks) == 0 {
+			for _, context := range checks.Contexts {
+				params.RequiredStatusChecks = append(params.RequiredStatusChecks, github.RuleRequiredStatusChecks{Context: context})
+			}
+		}
+		if len(params.RequiredStatusChecks) > 0 {
+			ruleset.Rules = append(ruleset.Rules, github.NewRequiredStatusChecksRule(params))
+		}
+	}
+
+	if p.GetRequireLinearHistory().Enabled {
+		ruleset.Rules = append(ruleset.Rules, github.NewRequiredLinearHistoryRule())
+	}
+	if p.RequiredSignatures.GetEnabled() {
+		ruleset.Rules = append(ruleset.Rules, github.NewRequiredSignaturesRule())
+	}
+	if !p.GetAllowForcePushes().Enabled {
+		ruleset.Rules = append(ruleset.Rules, github.NewNonFastForwardRule())
+	}
+	if !p.GetAllowDeletions().Enabled {
+		ruleset.Rules = append(ruleset.Rules, github.NewDeletionRule())
+	}
+	if p.GetLockBranch().GetEnabled() {
+		ruleset.Rules = append(ruleset.Rules, github.NewUpdateRule(nil))
+	}
+
+	// Without "include administrators", admins could bypass the protection
+	if !p.GetEnforceAdmins().Enabled {
+		ruleset.BypassActors = []*github.BypassActor{{
+			ActorID:    github.Int64(repositoryAdminRoleID),
+			ActorType:  github.String("RepositoryRole"),
+			BypassMode: github.String("always"),
+		}}
+	}
+
+	if r := p.Restrictions; r != nil && hasActors(r.Users, r.Teams, r.Apps) {
+		warnings = append(warnings, "push restrictions to specific users, teams or apps were not copied; configure them on the new repository by hand")
+	}
+	return ruleset, warnings
+}
+
+func hasActors(users []*github.User, teams []*github.Team, apps []*github.App) bool {
+	return len(users)+len(teams)+len(apps) > 0
+}
+
+// isPlanRestricted reports whether err is GitHub refusing a feature because
+// the account's plan doesn't include it. GitHub answers that with a 403 that
+// suggests upgrading; any other 403 or 404 (a missing branch or repository,
+// a token without the scope) is a failure of its own.
+func isPlanRestricted(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusForbidden {
+		return false
+	}
+	message := strings.ToLower(errResp.Message)
+	return strings.Contains(message, "upgrade to github pro") || strings.Contains(message, "not available")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// protectionServer serves protection as the branch protection of
+// octocat/project's main branch and records the protection PUT to it.
+func protectionServer(t *testing.T, protection string) (*github.Client, *map[string]any) {
+	t.Helper()
+	var put map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/octocat/project/branches/main/protection", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			io.WriteString(w, protection)
+		case http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&put); err != nil {
+				t.Errorf("decoding protection request: %v", err)
+			}
+			io.WriteString(w, protection)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return client, &put
+}
+
+func TestProtectBranchKeepsExistingProtection(t *testing.T) {
+	client, put := protectionServer(t, `{
+		"required_status_checks": {"strict": true, "contexts": ["ci"], "checks": [{"context": "ci", "app_id": 15368}]},
+		"enforce_admins": {"enabled": true},
+		"restrictions": {"users": [{"login": "octocat"}], "teams": [{"slug": "core"}], "apps": []},
+		"required_linear_history": {"enabled": true},
+		"allow_force_pushes": {"enabled": false},
+		"allow_deletions": {"enabled": false},
+		"required_conversation_resolution": {"enabled": true}
+	}`)
+
+	changed, err := protectBranch(context.Background(), client, "octocat", "project", "main", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("protectBranch reported no change for a branch without required reviews")
+	}
+
+	got, err := json.Marshal(*put)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"allow_deletions":false,"allow_force_pushes":false,"enforce_admins":true,` +
+		`"required_conversation_resolution":true,"required_linear_history":true,` +
+		`"required_pull_request_reviews":{"dismiss_stale_reviews":false,"require_code_owner_reviews":true,"required_approving_review_count":1},` +
+		`"required_status_checks":{"checks":[{"app_id":15368,"context":"ci"}],"strict":true},` +
+		`"restrictions":{"apps":[],"teams":["core"],"users":["octocat"]}}`
+	if string(got) != want {
+		t.Errorf("protection request\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProtectBranchLeavesStricterReviewsAlone(t *testing.T) {
+	client, put := protectionServer(t, `{
+		"required_status_checks": {"strict": true, "contexts": ["ci"]},
+		"required_pull_request_reviews": {"required_approving_review_count": 2, "require_code_owner_reviews": true},
+		"enforce_admins": {"enabled": true}
+	}`)
+
+	// Code owner reviews weren't asked for, but aren't turned off either
+	changed, err := protectBranch(context.Background(), client, "octocat", "project", "main", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("protectBranch reported a change for a branch that already requires reviews")
+	}
+	if *put != nil {
+		t.Errorf("protectBranch replaced the existing protection with %v", *put)
+	}
+}
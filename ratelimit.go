@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// rateQuota is the state of one GitHub rate limit.
+type rateQuota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// rateLimitStatus is the remaining API budget reported by
+// -print-rate-limit.
+type rateLimitStatus struct {
+	Core    rateQuota `json:"core"`
+	GraphQL rateQuota `json:"graphql"`
+}
+
+// fetchRateLimit asks GitHub for the remaining REST and GraphQL quotas.
+// Querying the rate limit doesn't count against it.
+func fetchRateLimit(ctx context.Context, client *github.Client) (*rateLimitStatus, error) {
+	limits, _, err := client.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+	return &rateLimitStatus{
+		Core:    toRateQuota(limits.GetCore()),
+		GraphQL: toRateQuota(limits.GetGraphQL()),
+	}, nil
+}
+
+func toRateQuota(r *github.Rate) rateQuota {
+	if r == nil {
+		return rateQuota{}
+	}
+	return rateQuota{Limit: r.Limit, Remaining: r.Remaining, Reset: r.Reset.Time}
+}
+
+// printRateLimit prints the remaining quotas and when they reset.
+func printRateLimit(status *rateLimitStatus) {
+	fmt.Println("Rate limit remaining:")
+	for _, q := range []struct {
+		name  string
+		quota rateQuota
+	}{{"core", status.Core}, {"graphql", status.GraphQL}} {
+		fmt.Printf("  %-8s %d/%d, resets at %s\n", q.name, q.quota.Remaining, q.quota.Limit, q.quota.Reset.Local().Format("15:04:05"))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryPolicy controls how often failed GitHub requests and pushes are
+// retried. The delay doubles with every attempt, starting at baseDelay.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+// retries is the policy for the current run, set from -max-retries and
+// -retry-base-delay.
+var retries = retryPolicy{maxRetries: 3, baseDelay: 500 * time.Millisecond}
+
+// maxRetryDelay caps the doubling delay between retries; maxRetries is the
+// most -max-retries allows.
+const (
+	maxRetryDelay = time.Minute
+	maxRetries    = 20
+)
+
+// delay returns how long to wait before the nth retry, counting from 1. It
+// doubles up to maxRetryDelay, which the shift can't overflow past.
+func (p retryPolicy) delay(n int) time.Duration {
+	d := p.baseDelay
+	for i := 1; i < n && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
+
+// do calls fn until it succeeds or the retries are used up, and returns the
+// last error. what describes the operation in warnings.
+func (p retryPolicy) do(what string, fn func() error) error {
+	err := fn()
+	for n := 1; err != nil && n <= p.maxRetries; n++ {
+		d := p.delay(n)
+		warnf("%s failed, retrying in %s (%d/%d): %v", what, d, n, p.maxRetries, err)
+		time.Sleep(d)
+		err = fn()
+	}
+	return err
+}
+
+// retryTransport retries requests that failed with a network error or a
+// response that is likely transient (429, 502, 503, 504, or a 403 from the
+// secondary rate limit). A Retry-After header is waited for instead of the
+// policy's delay, up to maxRetryDelay. Only idempotent requests are retried,
+// so a repository is never created twice.
+type retryTransport struct {
+	base   http.RoundTripper
+	policy retryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if !isIdempotent(req) {
+		return resp, err
+	}
+	for n := 1; n <= t.policy.maxRetries && retryableResponse(resp, err); n++ {
+		if req.Body != nil && req.GetBody == nil {
+			break
+		}
+		d := t.policy.delay(n)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				d = after
+			}
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(d):
+		}
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = t.base.RoundTrip(retry)
+	}
+	return resp, err
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		// GitHub's secondary rate limit says when to come back; other 403s
+		// are permanent
+		_, ok := retryAfter(resp)
+		return ok
+	}
+	return false
+}
+
+// retryAfter returns how long resp's Retry-After header asks to wait, given
+// in seconds or as an HTTP date, capped at maxRetryDelay.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(min(secs, int(maxRetryDelay/time.Second))) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = time.Until(at)
+	} else {
+		return 0, false
+	}
+	return min(max(d, 0), maxRetryDelay), true
+}
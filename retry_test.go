@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"soon", 0, false},
+		{"7", 7 * time.Second, true},
+		{"-3", 0, true},
+		{"86400", maxRetryDelay, true},
+		{"99999999999999999", maxRetryDelay, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), maxRetryDelay, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryTransportSecondaryRateLimit(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case calls == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	// The base delay is far longer than the test may take, so only
+	// honoring Retry-After lets the retry happen in time
+	client := &http.Client{Transport: &retryTransport{
+		base:   http.DefaultTransport,
+		policy: retryPolicy{maxRetries: 1, baseDelay: time.Hour},
+	}}
+	done := make(chan struct{})
+	var resp *http.Response
+	var err error
+	go func() {
+		defer close(done)
+		resp, err = client.Get(srv.URL + "/limited")
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the retry waited for the base delay instead of Retry-After")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("got %d after %d calls, want 200 after 2", resp.StatusCode, calls)
+	}
+
+	// A 403 without Retry-After is a permanent refusal
+	calls = 0
+	resp, err = client.Get(srv.URL + "/forbidden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || calls != 1 {
+		t.Errorf("got %d after %d calls, want 403 after 1", resp.StatusCode, calls)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/joho/godotenv"
+)
+
+// writeScaffoldFile writes a file generated for the initial commit. Content
+// from templates or from files edited on Windows may carry a byte order mark
+// or CRLF line endings; both are normalized away so the commit looks the same
+// on every platform.
+func writeScaffoldFile(path, content string) error {
+	return os.WriteFile(path, []byte(normalizeText(content)), 0o644)
+}
+
+// normalizeText strips a UTF-8 byte order mark, converts CRLF and lone CR
+// line endings to LF and makes sure non-empty text ends with a newline.
+func normalizeText(s string) string {
+	s = strings.TrimPrefix(s, "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// How -gitignore-mode combines templates with an existing .gitignore.
+const (
+	gitignoreKeep      = "keep"
+	gitignoreMerge     = "merge"
+	gitignoreOverwrite = "overwrite"
+)
+
+// fetchGitignoreTemplates fetches the source of each named template from
+// GitHub.
+func fetchGitignoreTemplates(ctx context.Context, client *github.Client, names []string) (map[string]string, error) {
+	sources := make(map[string]string, len(names))
+	for _, name := range names {
+		tmpl, _, err := client.Gitignores.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch gitignore template %q: %w", name, err)
+		}
+		sources[name] = tmpl.GetSource()
+	}
+	return sources, nil
+}
+
+// writeGitignoreTemplates combines the named templates, fetched into sources,
+// with the .gitignore at path according to mode: keep leaves an existing file
+// alone, overwrite replaces it, and merge appends to it. Every template gets
+// its own section header, and rules already present (in the merged file or in
+// an earlier template) are not repeated.
+func writeGitignoreTemplates(path string, names []string, sources map[string]string, mode string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		switch mode {
+		case gitignoreKeep:
+			fmt.Printf("Keeping existing %s (-gitignore-mode=keep)\n", path)
+			return nil
+		case gitignoreOverwrite:
+			existing = nil
+		}
+	}
+
+	content := string(existing)
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		seen[strings.TrimSpace(line)] = true
+	}
+
+	for _, name := range names {
+		header := fmt.Sprintf("### %s ###", name)
+		if seen[header] {
+			continue
+		}
+
+		var section []string
+		for _, line := range strings.Split(sources[name], "\n") {
+			trimmed := strings.TrimSpace(line)
+			isRule := trimmed != "" && !strings.HasPrefix(trimmed, "#")
+			if isRule && seen[trimmed] {
+				continue
+			}
+			seen[trimmed] = true
+			section = append(section, strings.TrimRight(line, " \t\r"))
+		}
+
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += header + "\n" + strings.TrimSpace(strings.Join(section, "\n")) + "\n"
+		seen[header] = true
+	}
+
+	return writeScaffoldFile(path, content)
+}
+
+// licensePlaceholders are the placeholder spellings used across GitHub's
+// license templates for the copyright year and holder.
+var (
+	licenseYearPlaceholders   = []string{"[year]", "[yyyy]", "<year>"}
+	licenseHolderPlaceholders = []string{"[fullname]", "[name of copyright owner]", "<name of author>", "<copyright holders>"}
+)
+
+// licenseYearPattern matches the values -license-year accepts: a year or a
+// range of years.
+var licenseYearPattern = regexp.MustCompile(`^[0-9]{4}(-[0-9]{4})?$`)
+
+// fetchLicense fetches the license identified by key (an SPDX id such as
+// "mit") and returns its text with the year and copyright holder filled in.
+func fetchLicense(ctx context.Context, client *github.Client, key, year, holder string) (string, error) {
+	license, _, err := client.Licenses.Get(ctx, strings.ToLower(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch license %q: %w", key, err)
+	}
+
+	body := license.GetBody()
+	for _, p := range licenseYearPlaceholders {
+		body = strings.ReplaceAll(body, p, year)
+	}
+	for _, p := range licenseHolderPlaceholders {
+		body = strings.ReplaceAll(body, p, holder)
+	}
+	return body, nil
+}
+
+// errLicenseExists is returned by writeLicense when there already is a
+// license file, which is kept as it is.
+var errLicenseExists = errors.New("license file already exists")
+
+// writeLicense writes the license text body to path. An existing file is
+// left untouched and errLicenseExists returned.
+func writeLicense(path, body string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errLicenseExists
+	}
+	return writeScaffoldFile(path, body)
+}
+
+// writeEnvExample writes a copy of the dotenv file at envPath next to it with
+// a .example suffix, keeping comments and keys but blanking every value, and
+// returns the path it wrote. The continuation lines of a quoted multi-line
+// value (a PEM key, say) are dropped along with the rest of the value.
+func writeEnvExample(envPath string) (string, error) {
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return "", err
+	}
+	// godotenv has the final say on the keys, so a value this doesn't
+	// recognize as multi-line can't leak into the example
+	env, err := godotenv.Unmarshal(string(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", envPath, err)
+	}
+
+	var lines []string
+	keys := make(map[string]bool)
+	var quote byte // the quote a multi-line value is still open in
+	for _, line := range strings.Split(string(data), "\n") {
+		if quote != 0 {
+			if closesQuote(line, quote) {
+				quote = 0
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		lines = append(lines, key+"=")
+		keys[strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(key), "export "))] = true
+		if value = strings.TrimSpace(value); value != "" && strings.ContainsRune("\"'`", rune(value[0])) && !closesQuote(value[1:], value[0]) {
+			quote = value[0]
+		}
+	}
+	for key := range env {
+		if !keys[key] {
+			return "", fmt.Errorf("failed to blank the value of %s in %s; write the example by hand", key, envPath)
+		}
+	}
+	for key := range keys {
+		if _, ok := env[key]; !ok {
+			return "", fmt.Errorf("failed to tell the keys of %s from its values; write the example by hand", envPath)
+		}
+	}
+
+	examplePath := envPath + ".example"
+	if err := writeScaffoldFile(examplePath, strings.Join(lines, "\n")); err != nil {
+		return "", err
+	}
+	return examplePath, nil
+}
+
+// closesQuote reports whether s contains the closing quote of a value.
+// Double quotes can be escaped with a backslash.
+func closesQuote(s string, quote byte) bool {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return true
+		}
+	}
+	return false
+}
+
+// defaultChangelog is the CHANGELOG.md written by -changelog, following
+// https://keepachangelog.com.
+const defaultChangelog = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+## [Unreleased]
+`
+
+// writeChangelog writes defaultChangelog, or the file at templatePath if it
+// is set, to path. It reports false and leaves the file alone if path already
+// exists.
+func writeChangelog(path, templatePath string) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	content := defaultChangelog
+	if templatePath != "" {
+		data, err := os.ReadFile(templatePath)
+		if err != nil {
+			return false, err
+		}
+		content = string(data)
+	}
+	return true, writeScaffoldFile(path, content)
+}
+
+// writeGitkeeps writes an empty .gitkeep into every empty directory below
+// root that git doesn't ignore, so that the directories survive the commit,
+// and returns the paths it wrote. Directories that contain anything, even
+// just other directories, are left alone.
+func writeGitkeeps(root string) ([]string, error) {
+	keeps, err := gitkeepPaths(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, keep := range keeps {
+		if err := os.WriteFile(keep, nil, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return keeps, nil
+}
+
+// gitkeepPaths returns the .gitkeep files writeGitkeeps would write below
+// root.
+func gitkeepPaths(root string) ([]string, error) {
+	var keeps []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if d.Name() == ".git" || gitCommand("check-ignore", "-q", path).Run() == nil {
+			return filepath.SkipDir
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil || len(entries) > 0 {
+			return err
+		}
+		keeps = append(keeps, filepath.Join(path, ".gitkeep"))
+		return nil
+	})
+	return keeps, err
+}
+
+// teamHandlePattern matches team handles such as @org/team, as used in
+// CODEOWNERS.
+var teamHandlePattern = regexp.MustCompile(`^@[A-Za-z0-9][A-Za-z0-9-]*/[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// writeCodeowners makes owner the default owner of every file in the
+// CODEOWNERS file at path. The "* owner" rule goes first, so more specific
+// rules already in the file keep taking precedence. A file that already has
+// a catch-all rule is left alone.
+func writeCodeowners(path, owner string) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if rule, ok := defaultOwnerRule(string(existing)); ok {
+		if !slices.Contains(strings.Fields(rule)[1:], owner) {
+			warnf("%s already has a default owner (%s); leaving it as is", path, rule)
+		}
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	return true, writeScaffoldFile(path, "* "+owner+"\n"+string(existing))
+}
+
+// defaultOwnerRule returns the catch-all "* owner" rule of the CODEOWNERS
+// content, if it has one.
+func defaultOwnerRule(content string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "*" {
+			return strings.TrimSpace(line), true
+		}
+	}
+	return "", false
+}
+
+// ensureGitignored appends pattern to the .gitignore at path unless it is
+// already listed there.
+func ensureGitignored(path, pattern string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	content := string(existing)
+	if gitignoreLists(content, pattern) {
+		return nil
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return writeScaffoldFile(path, content+pattern+"\n")
+}
+
+// gitignoreLists reports whether the .gitignore content lists pattern.
+func gitignoreLists(content, pattern string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == pattern || line == "/"+pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Values of -scan-secrets.
+const (
+	scanSecretsBlock = "block"
+	scanSecretsWarn  = "warn"
+)
+
+// secretRule is a pattern for a likely secret.
+type secretRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	re      *regexp.Regexp
+}
+
+// secretRules is the rule set -scan-secrets applies, as read from
+// -secret-rules.
+type secretRules struct {
+	Rules []secretRule `json:"rules"`
+	// EntropyThreshold flags long random-looking strings with at least
+	// this many bits of entropy per character; 0 turns the check off.
+	EntropyThreshold *float64 `json:"entropy_threshold"`
+}
+
+// defaultSecretRules are known token formats that are rarely anything else.
+var defaultSecretRules = []secretRule{
+	{Name: "GitHub token", Pattern: `\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`},
+	{Name: "GitHub fine-grained token", Pattern: `\bgithub_pat_[A-Za-z0-9_]{82}\b`},
+	{Name: "AWS access key ID", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "AWS secret access key", Pattern: `(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`},
+	{Name: "Slack token", Pattern: `\bxox[abpors]-[A-Za-z0-9-]{10,}`},
+	{Name: "Stripe secret key", Pattern: `\b[rs]k_live_[A-Za-z0-9]{24,}`},
+	{Name: "Google API key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{Name: "private key", Pattern: `-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`},
+}
+
+// defaultEntropyThreshold is the entropy per character above which a long
+// token counts as random. English text and identifiers stay well below it.
+const defaultEntropyThreshold = 4.5
+
+// entropyCandidate matches tokens long enough to be a key or password.
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// checksumFiles are lock files full of base64 checksums, which the entropy
+// check would flag line by line.
+var checksumFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+	"Gemfile.lock":      true,
+}
+
+// readSecretRules reads a -secret-rules file. Its rules replace the built-in
+// ones; without rules only the entropy threshold is changed.
+func readSecretRules(path string) (*secretRules, error) {
+	rules := &secretRules{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, rules); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if len(rules.Rules) == 0 {
+		rules.Rules = append([]secretRule{}, defaultSecretRules...)
+	}
+	if rules.EntropyThreshold == nil {
+		threshold := defaultEntropyThreshold
+		rules.EntropyThreshold = &threshold
+	}
+	for i := range rules.Rules {
+		r := &rules.Rules[i]
+		if r.Name == "" {
+			r.Name = r.Pattern
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %w", path, r.Name, err)
+		}
+		r.re = re
+	}
+	return rules, nil
+}
+
+// secretFinding is a line of the staged changes that looks like a secret.
+type secretFinding struct {
+	path string
+	line int
+	rule string
+}
+
+func (f secretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.path, f.line, f.rule)
+}
+
+// scanStagedSecrets checks the lines added by the staged changes against
+// rules. Binary files are skipped.
+func scanStagedSecrets(rules *secretRules) ([]secretFinding, error) {
+	out, err := gitCommand("diff", "--cached", "--no-color", "--no-ext-diff", "-U0").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []secretFinding
+	var path string
+	line := 0
+	// The file header runs from "diff --git" to the first hunk; telling it
+	// apart keeps an added line starting with "++ " from passing as one
+	inHeader := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			inHeader = true
+		case inHeader && strings.HasPrefix(text, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+		case strings.HasPrefix(text, "@@ "):
+			inHeader = false
+			// @@ -a,b +c,d @@: added lines are numbered from c
+			_, after, _ := strings.Cut(text, " +")
+			start, _, _ := strings.Cut(after, " ")
+			start, _, _ = strings.Cut(start, ",")
+			line, _ = strconv.Atoi(start)
+		case !inHeader && strings.HasPrefix(text, "+"):
+			if rule := matchSecret(text[1:], rules, !checksumFiles[filepath.Base(path)]); rule != "" {
+				findings = append(findings, secretFinding{path: path, line: line, rule: rule})
+			}
+			line++
+		}
+	}
+	return findings, scanner.Err()
+}
+
+// matchSecret returns the name of the first rule line matches, or an empty
+// string. The entropy check only applies with entropy set.
+func matchSecret(line string, rules *secretRules, entropy bool) string {
+	for _, r := range rules.Rules {
+		if r.re.MatchString(line) {
+			return r.Name
+		}
+	}
+	if threshold := *rules.EntropyThreshold; entropy && threshold > 0 {
+		for _, token := range entropyCandidate.FindAllString(line, -1) {
+			if shannonEntropy(token) >= threshold {
+				return "high-entropy string"
+			}
+		}
+	}
+	return ""
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
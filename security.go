@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// enableSecretScanning turns on secret scanning for owner/repo and, with
+// pushProtection, blocks pushes that contain secrets. It returns a
+// description of what is enabled, or an unchangedStep error if it already
+// was.
+func enableSecretScanning(ctx context.Context, client *github.Client, owner, repo string, pushProtection bool) (string, error) {
+	current, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s/%s: %w", owner, repo, err)
+	}
+	if sa := current.GetSecurityAndAnalysis(); sa.GetSecretScanning().GetStatus() == "enabled" {
+		if !pushProtection {
+			return "", unchangedStep("secret scanning enabled")
+		}
+		if sa.GetSecretScanningPushProtection().GetStatus() == "enabled" {
+			return "", unchangedStep("secret scanning and push protection enabled")
+		}
+	}
+
+	security := &github.SecurityAndAnalysis{
+		SecretScanning: &github.SecretScanning{Status: github.String("enabled")},
+	}
+	if pushProtection {
+		security.SecretScanningPushProtection = &github.SecretScanningPushProtection{Status: github.String("enabled")}
+	}
+
+	updated, _, err := client.Repositories.Edit(ctx, owner, repo, &github.Repository{SecurityAndAnalysis: security})
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if isPlanRestricted(err) || (errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnprocessableEntity) {
+			return "", fmt.Errorf("secret scanning is not available for %s/%s: it's free for public repositories, but private ones need GitHub Advanced Security, and enabling it needs admin access: %w", owner, repo, err)
+		}
+		return "", fmt.Errorf("failed to enable secret scanning: %w", err)
+	}
+
+	// GitHub may accept the request without enabling anything
+	got := updated.GetSecurityAndAnalysis()
+	if got.GetSecretScanning().GetStatus() != "enabled" {
+		return "", fmt.Errorf("GitHub didn't enable secret scanning for %s/%s; it may not be available for this account or plan", owner, repo)
+	}
+	if pushProtection {
+		if got.GetSecretScanningPushProtection().GetStatus() != "enabled" {
+			return "", fmt.Errorf("GitHub enabled secret scanning but not push protection for %s/%s; it may not be available for this account or plan", owner, repo)
+		}
+		return "secret scanning and push protection enabled", nil
+	}
+	return "secret scanning enabled", nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// repoSettings returns the repository settings requested on the command line,
+// or nil if there are none. The same settings are sent with Create for new
+// repositories and applied with Edit for existing ones.
+func repoSettings(opts *options) *github.Repository {
+	settings := &github.Repository{}
+	changed := false
+
+	if opts.description != "" {
+		settings.Description = github.String(opts.description)
+		changed = true
+	}
+
+	if opts.autoMerge {
+		settings.AllowAutoMerge = github.Bool(true)
+		changed = true
+	}
+
+	if opts.allowUpdateBranch {
+		settings.AllowUpdateBranch = github.Bool(true)
+		changed = true
+	}
+
+	if opts.squashTitle != "" {
+		settings.SquashMergeCommitTitle = github.String(opts.squashTitle)
+		changed = true
+	}
+	if opts.squashMessage != "" {
+		settings.SquashMergeCommitMessage = github.String(opts.squashMessage)
+		changed = true
+	}
+	if opts.mergeTitle != "" {
+		settings.MergeCommitTitle = github.String(opts.mergeTitle)
+		changed = true
+	}
+	if opts.mergeMessage != "" {
+		settings.MergeCommitMessage = github.String(opts.mergeMessage)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return settings
+}
+
+// applyRepoSettings edits an existing repository to match settings and
+// returns the updated repository.
+func applyRepoSettings(ctx context.Context, client *github.Client, repo *github.Repository, settings *github.Repository) (*github.Repository, error) {
+	updated, _, err := client.Repositories.Edit(ctx, repo.GetOwner().GetLogin(), repo.GetName(), settings)
+	if err != nil {
+		return repo, fmt.Errorf("failed to update repository settings: %w", err)
+	}
+	return updated, nil
+}
+
+// renameRepo renames repo to name. GitHub redirects the old name to the new
+// one, but the returned repository carries the new URLs to use from here on.
+func renameRepo(ctx context.Context, client *github.Client, repo *github.Repository, name string) (*github.Repository, error) {
+	renamed, _, err := client.Repositories.Edit(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.Repository{Name: github.String(name)})
+	if err != nil {
+		return repo, fmt.Errorf("failed to rename %s to %s: %w", repo.GetFullName(), name, err)
+	}
+	return renamed, nil
+}
+
+// reportRepoSettings prints the requested settings as GitHub reports them.
+// GitHub silently ignores settings the account can't use, so a requested
+// setting that didn't stick is reported as unavailable.
+func reportRepoSettings(opts *options, repo *github.Repository) {
+	if opts.autoMerge {
+		if repo.GetAllowAutoMerge() {
+			fmt.Println("Auto-merge: enabled")
+		} else {
+			warnf("auto-merge could not be enabled; it may not be available for this account or plan")
+		}
+	}
+	if opts.allowUpdateBranch {
+		if repo.GetAllowUpdateBranch() {
+			fmt.Println("Suggest updating pull request branches: enabled")
+		} else {
+			warnf("suggesting to update pull request branches could not be enabled; it may not be available for this account or plan")
+		}
+	}
+}
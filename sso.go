@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// ssoAuthorizationURL reports whether err is the 403 GitHub returns when an
+// organization enforces SAML single sign-on and the token hasn't been
+// authorized for it. If so it also returns the URL from the X-GitHub-SSO
+// header at which the token can be authorized, when GitHub sent one.
+func ssoAuthorizationURL(err error) (string, bool) {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusForbidden {
+		return "", false
+	}
+	header := errResp.Response.Header.Get("X-GitHub-SSO")
+	if header == "" {
+		return "", false
+	}
+	// e.g. "required; url=https://github.com/orgs/acme/sso?authorization_request=..."
+	for _, part := range strings.Split(header, ";") {
+		if url, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+			return url, true
+		}
+	}
+	return "", true
+}
+
+// explainSSO turns an SSO 403 into an error telling the user how to authorize
+// their token; other errors are returned unchanged.
+func explainSSO(err error) error {
+	url, ok := ssoAuthorizationURL(err)
+	if !ok {
+		return err
+	}
+	if url == "" {
+		return fmt.Errorf("the organization enforces SAML single sign-on and your token isn't authorized for it; authorize it under Settings > Developer settings > Personal access tokens > Configure SSO (%w)", err)
+	}
+	return fmt.Errorf("the organization enforces SAML single sign-on and your token isn't authorized for it; authorize it at %s and run repoinit again (%w)", url, err)
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Messages of the commits repoinit creates: the initial commit, or the commit
+// on top of the one GitHub created with -auto-init.
+const (
+	initialCommitMessage      = "Initial commit"
+	projectFilesCommitMessage = "Add project files"
+)
+
+// splitCommitTrailer marks the later commits of -split-commit as repoinit's
+// own; users write "Add dir/" commits too.
+const splitCommitTrailer = "Repoinit-Split-Commit"
+
+// isOwnCommit reports whether a commit with message was made by repoinit,
+// with its default messages or the -commit-message given.
+func isOwnCommit(message, customMessage string) bool {
+	subject, body, _ := strings.Cut(message, "\n")
+	subject = strings.TrimSpace(subject)
+	if dir, ok := strings.CutPrefix(subject, "Add "); ok && strings.HasSuffix(dir, "/") {
+		for _, line := range strings.Split(body, "\n") {
+			if strings.TrimSpace(line) == splitCommitTrailer+": "+dir {
+				return true
+			}
+		}
+	}
+	if customMessage != "" {
+		first, _, _ := strings.Cut(customMessage, "\n")
+		return subject == strings.TrimSpace(first)
+	}
+	return subject == initialCommitMessage || subject == projectFilesCommitMessage
+}
+
+// coAuthorPattern matches "Name <email>", the form GitHub expects in
+// Co-authored-by trailers.
+var coAuthorPattern = regexp.MustCompile(`^[^<>]+ <[^<>\s]+@[^<>\s]+>$`)
+
+// withCoAuthors appends a Co-authored-by trailer for each co-author to
+// message.
+func withCoAuthors(message string, coAuthors []string) string {
+	if len(coAuthors) == 0 {
+		return message
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(message, "\n"))
+	b.WriteString("\n\n")
+	for _, author := range coAuthors {
+		b.WriteString("Co-authored-by: " + author + "\n")
+	}
+	return b.String()
+}
+
+// Values of -stage-mode.
+const (
+	stageAll         = "all"
+	stageTracked     = "tracked"
+	stageInteractive = "interactive"
+)
+
+// stagePaths returns the paths that go into the initial commit, in the order
+// they are staged: .gitignore first, so its rules apply to everything after
+// it, then .gitmodules and the checked-out submodules, which are staged as
+// references to their commits, then every non-hidden file and directory in
+// the current directory that .gitignore doesn't exclude. Directories are
+// staged whole, minus what is ignored inside them. Hidden entries such as
+// .env stay out; list them in -manifest to commit them.
+func stagePaths() ([]string, error) {
+	var paths []string
+	if _, err := os.Stat(".gitignore"); err == nil {
+		paths = append(paths, ".gitignore")
+	}
+	subs, err := declaredSubmodules()
+	if err != nil {
+		return nil, err
+	}
+	if subs != nil {
+		paths = append(paths, ".gitmodules")
+	}
+	for _, sub := range subs {
+		if sub.checkedOut {
+			paths = append(paths, sub.path)
+		}
+	}
+
+	files, err := os.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".") || slices.Contains(paths, name) {
+			continue
+		}
+		if gitCommand("check-ignore", "-q", name).Run() == nil {
+			continue
+		}
+		paths = append(paths, name)
+	}
+	return paths, nil
+}
+
+// appendUncovered appends those of extra to paths that aren't already
+// staged through one of them, such as a file inside a listed directory.
+func appendUncovered(paths []string, extra ...string) []string {
+	covered := func(path string) bool {
+		for _, p := range paths {
+			if path == p || strings.HasPrefix(filepath.ToSlash(path), filepath.ToSlash(p)+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	for _, path := range extra {
+		if path != "" && !covered(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// readManifest reads the -manifest file at path: one path per line, in the
+// order they should be staged. Blank lines and lines starting with # are
+// ignored. Every listed path must exist.
+func readManifest(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := os.Stat(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s does not exist", path, i+1, line)
+		}
+		paths = append(paths, line)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s lists no paths", path)
+	}
+	return paths, nil
+}
+
+// selectPaths asks about each of paths and returns those the user wants to
+// stage; a directory is asked about once, as a whole. .gitignore is always
+// kept, as it decides what the other paths may contain.
+func selectPaths(paths []string) ([]string, error) {
+	var selected []string
+	for i, path := range paths {
+		if path == ".gitignore" {
+			selected = append(selected, path)
+			continue
+		}
+		name := path
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			name += "/"
+		}
+		answer, err := prompt(fmt.Sprintf("Stage %s? [Y/n/a/q] ", name))
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(answer) {
+		case "", "y", "yes":
+			selected = append(selected, path)
+		case "a", "all":
+			return append(selected, paths[i:]...), nil
+		case "q", "quit":
+			return selected, nil
+		}
+	}
+	return selected, nil
+}
+
+// stageTrackedFiles stages the changes to files git already tracks, leaving
+// untracked files out.
+func stageTrackedFiles() error {
+	return runGit("add", "-u")
+}
+
+// stageFiles adds paths to the index one at a time, warning about (rather
+// than failing on) paths git refuses to add.
+func stageFiles(paths []string) {
+	for _, path := range paths {
+		if err := runGit("add", path); err != nil {
+			warnf("Failed to add %s: %v", path, err)
+		}
+	}
+}
+
+// maxListedFiles is how many staged paths -verbose lists before falling back
+// to a count; -list-files always lists all of them.
+const maxListedFiles = 50
+
+// stagedFiles returns the paths whose staged content differs from HEAD.
+func stagedFiles() ([]string, error) {
+	out, err := gitOutput("diff", "--cached", "--name-only")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// commitGroup is a set of staged paths committed together by -split-commit.
+type commitGroup struct {
+	// dir is the top-level directory of the paths, or empty for the files
+	// at the top level.
+	dir   string
+	paths []string
+}
+
+// stagedGroups groups the staged paths by top-level directory. The files at
+// the top level come first, then the directories in order.
+func stagedGroups() ([]commitGroup, error) {
+	out, err := gitCommand("diff", "--cached", "--name-only", "-z").Output()
+	if err != nil {
+		return nil, err
+	}
+	byDir := map[string][]string{}
+	for _, path := range strings.Split(strings.TrimRight(string(out), "\x00"), "\x00") {
+		if path == "" {
+			continue
+		}
+		dir, _, nested := strings.Cut(path, "/")
+		if !nested {
+			dir = ""
+		}
+		byDir[dir] = append(byDir[dir], path)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	groups := make([]commitGroup, 0, len(dirs))
+	for _, dir := range dirs {
+		groups = append(groups, commitGroup{dir: dir, paths: byDir[dir]})
+	}
+	return groups, nil
+}
+
+// groupCommitMessage is the message for committing g with -split-commit:
+// the first commit, the top-level files if there are any, gets the initial
+// commit message, each further directory a commit of its own.
+func groupCommitMessage(g commitGroup, message string, first bool) string {
+	if first || g.dir == "" {
+		return message
+	}
+	return fmt.Sprintf("Add %s/\n\n%s: %s/\n", g.dir, splitCommitTrailer, g.dir)
+}
+
+// printStagedFiles lists the staged paths, or just counts them if there are
+// more than maxListedFiles and all is false.
+func printStagedFiles(paths []string, all bool) {
+	if len(paths) > maxListedFiles && !all {
+		fmt.Printf("Staged %d files (use -list-files to list them)\n", len(paths))
+		return
+	}
+	fmt.Printf("Staged %d files:\n", len(paths))
+	for _, path := range paths {
+		fmt.Printf("  %s\n", path)
+	}
+}
+
+// commitPreview describes what the initial commit would contain.
+type commitPreview struct {
+	// Changes holds one "<status>\t<path>" line per file, as printed by
+	// git diff --name-status.
+	Changes []string
+	// Stat is git's diffstat summary line. It doesn't count the files in
+	// Generated, which don't exist yet.
+	Stat string
+	// Generated are the paths in Changes that repoinit would write.
+	Generated []string
+}
+
+// emptyTree is the id of git's empty tree, which every repository knows.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// previewCommit stages paths into a throwaway index, the way the run would
+// stage them under opts, and reports what the initial commit would contain,
+// including the files prepareLocal would generate. The user's repository is
+// never modified: an existing index is copied rather than written to, the
+// blobs git add creates go to a temporary object directory, and a directory
+// that isn't a repository yet is staged against a temporary one.
+//
+// The preview stages with git itself rather than an in-memory Go
+// implementation of the index: the real run stages with git, so the preview
+// applies the same .gitignore rules, attributes and config, and repoinit
+// keeps depending on nothing but the git it already requires.
+func previewCommit(opts *options, paths []string) (*commitPreview, error) {
+	tmp, err := os.MkdirTemp("", "repoinit-preview")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	index := filepath.Join(tmp, "index")
+	env := append(os.Environ(), "GIT_INDEX_FILE="+index)
+	var prefix []string
+	if isInitialized() {
+		objects, err := gitOutput("rev-parse", "--path-format=absolute", "--git-path", "objects")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Mkdir(filepath.Join(tmp, "objects"), 0o700); err != nil {
+			return nil, err
+		}
+		env = append(env, "GIT_OBJECT_DIRECTORY="+filepath.Join(tmp, "objects"), "GIT_ALTERNATE_OBJECT_DIRECTORIES="+objects)
+		// -subtree commits dir into a fresh tree, not onto the index
+		if opts.subtree == "" {
+			gitDir, err := gitOutput("rev-parse", "--git-dir")
+			if err != nil {
+				return nil, err
+			}
+			if data, err := os.ReadFile(filepath.Join(gitDir, "index")); err == nil {
+				if err := os.WriteFile(index, data, 0o600); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		scratch := filepath.Join(tmp, "repo")
+		if err := gitCommand("init", "-q", scratch).Run(); err != nil {
+			return nil, fmt.Errorf("failed to create scratch repository: %w", err)
+		}
+		prefix = []string{"--git-dir", filepath.Join(scratch, ".git"), "--work-tree", "."}
+	}
+
+	git := func(args ...string) (string, error) {
+		cmd := gitCommand(append(append([]string{}, prefix...), args...)...)
+		cmd.Env = env
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	if opts.subtree != "" {
+		return previewSubtree(git, opts.subtree)
+	}
+
+	if opts.stageMode == stageTracked {
+		if _, err := git("add", "-u"); err != nil {
+			return nil, fmt.Errorf("failed to stage tracked files: %w", err)
+		}
+	} else {
+		for _, path := range paths {
+			if _, err := git("add", "--", path); err != nil {
+				warnf("%s would not be added: %v", path, err)
+			}
+		}
+	}
+
+	names, err := git("diff", "--cached", "--name-status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff preview index: %w", err)
+	}
+	stat, err := git("diff", "--cached", "--shortstat")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff preview index: %w", err)
+	}
+
+	preview := &commitPreview{Stat: stat}
+	if names != "" {
+		preview.Changes = strings.Split(names, "\n")
+	}
+
+	// The generated files don't exist yet, or don't have their new content.
+	// The run stages them along with everything else, but with -manifest
+	// or -stage-mode tracked only if they are listed or tracked.
+	generated, err := plannedScaffolding(opts)
+	if err != nil {
+		return nil, err
+	}
+	everything := opts.manifestPaths == nil && opts.stageMode != stageTracked
+	for _, path := range generated {
+		_, trackedErr := git("ls-files", "--error-unmatch", "--", path)
+		tracked := trackedErr == nil
+		if !everything && !tracked && !slices.Contains(paths, path) {
+			continue
+		}
+		status := "A"
+		if tracked {
+			status = "M"
+		}
+		preview.Changes = slices.DeleteFunc(preview.Changes, func(change string) bool {
+			_, p, _ := strings.Cut(change, "\t")
+			return p == path
+		})
+		preview.Changes = append(preview.Changes, status+"\t"+path)
+		preview.Generated = append(preview.Generated, path)
+	}
+	return preview, nil
+}
+
+// previewSubtree reports what commitSubtree would commit for dir, staging it
+// with git into an empty index. Paths are relative to dir, as they are in
+// the commit.
+func previewSubtree(git func(args ...string) (string, error), dir string) (*commitPreview, error) {
+	if _, err := git("add", "-A", "--", dir); err != nil {
+		return nil, fmt.Errorf("failed to stage %s: %w", dir, err)
+	}
+	files, err := git("ls-files", "--", dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	stat, err := git("diff", "--cached", "--shortstat", emptyTree, "--", dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff preview index: %w", err)
+	}
+	preview := &commitPreview{Stat: stat}
+	root := filepath.ToSlash(filepath.Clean(dir)) + "/"
+	for _, path := range strings.Split(files, "\n") {
+		if path != "" {
+			preview.Changes = append(preview.Changes, "A\t"+strings.TrimPrefix(path, root))
+		}
+	}
+	return preview, nil
+}
+
+// printDryRun describes the repository that would be created and the files
+// the initial commit would contain when staging paths under opts.
+func printDryRun(opts *options, owner, name string, paths []string) error {
+	preview, err := previewCommit(opts, paths)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Dry run: nothing will be created, committed or pushed.")
+	fmt.Printf("Repository: %s/%s\n", owner, name)
+	if opts.subtree == "" {
+		branch, working := plannedBranches(opts)
+		fmt.Printf("Branch: %s\n", branch)
+		if working != "" {
+			fmt.Printf("Working branch: %s\n", working)
+		}
+	}
+	if opts.since != "" {
+		fmt.Printf("History: only the commits from %s on are published\n", opts.since)
+	}
+	if opts.autoTopics {
+		detected := detectTopics()
+		fmt.Printf("Detected topics: %s\n", strings.Join(detected, ", "))
+		fmt.Printf("Topics: %s\n", strings.Join(mergeTopics(opts.topics, detected), ", "))
+	}
+	if opts.subtree != "" {
+		fmt.Printf("Initial commit (a fresh commit of %s):\n", opts.subtree)
+	} else {
+		fmt.Println("Initial commit:")
+	}
+	if len(preview.Changes) == 0 {
+		fmt.Println("  (no changes)")
+	}
+	for _, change := range preview.Changes {
+		_, path, _ := strings.Cut(change, "\t")
+		if slices.Contains(preview.Generated, path) {
+			change += " (generated)"
+		}
+		fmt.Printf("  %s\n", change)
+	}
+	if preview.Stat != "" {
+		fmt.Printf(" %s\n", preview.Stat)
+	}
+	if n := len(preview.Generated); n > 0 {
+		fmt.Printf(" plus %d file(s) generated by repoinit\n", n)
+	}
+	return nil
+}
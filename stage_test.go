@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates each of files, and the directories leading to it, in
+// the current directory.
+func writeFiles(t *testing.T, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStagePathsIncludesDirectories(t *testing.T) {
+	chdirTemp(t)
+	if err := runGit("init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, "main.go", "cmd/app/main.go", "build/app", ".env", ".idea/workspace.xml")
+	if err := os.WriteFile(".gitignore", []byte("build/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := stagePaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".gitignore", "cmd", "main.go"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("stagePaths() = %q, want %q", paths, want)
+	}
+}
+
+func TestAppendUncovered(t *testing.T) {
+	paths := []string{".gitignore", "cmd", "main.go"}
+	got := appendUncovered(paths, "", "cmd/app/.gitkeep", ".github/FUNDING.yml", "main.go", "cmdline/.gitkeep")
+	want := []string{".gitignore", "cmd", "main.go", ".github/FUNDING.yml", "cmdline/.gitkeep"}
+	if !slices.Equal(got, want) {
+		t.Errorf("appendUncovered() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitCommitSubdirectories(t *testing.T) {
+	chdirTemp(t)
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "Test")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+	if err := runGit("init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, "main.go", "cmd/app/main.go", "docs/index.md", "docs/guide/setup.md")
+
+	paths, err := stagePaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageFiles(paths)
+	groups, err := stagedGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, g := range groups {
+		if err := commitPaths(groupCommitMessage(g, initialCommitMessage, i == 0), g.paths); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revs, err := gitOutput("rev-list", "--reverse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ message, files string }{
+		{initialCommitMessage, "main.go"},
+		{"Add cmd/\n\n" + splitCommitTrailer + ": cmd/", "cmd/app/main.go"},
+		{"Add docs/\n\n" + splitCommitTrailer + ": docs/", "docs/guide/setup.md\ndocs/index.md"},
+	}
+	commits := strings.Fields(revs)
+	if len(commits) != len(want) {
+		t.Fatalf("got %d commits, want %d", len(commits), len(want))
+	}
+	for i, rev := range commits {
+		message, err := gitOutput("log", "-1", "--format=%B", rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := gitOutput("diff-tree", "--root", "--no-commit-id", "--name-only", "-r", rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message != want[i].message || files != want[i].files {
+			t.Errorf("commit %d = %q with %q, want %q with %q", i+1, message, files, want[i].message, want[i].files)
+		}
+		if i > 0 && !isOwnCommit(message, "") {
+			t.Errorf("isOwnCommit(%q) = false, want true", message)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// step is an action run after the repository has been created and pushed,
+// such as setting topics or protection rules. Steps are independent: one
+// failing doesn't stop the others. Steps check the current state before
+// changing anything, so a run can be repeated after a partial failure.
+type step struct {
+	name string
+	// required steps make the run fail if they fail.
+	required bool
+	// run performs the step and returns a short description of the outcome.
+	run func() (string, error)
+}
+
+// Step statuses reported in the summary and in --json output.
+const (
+	stepOK        = "ok"
+	stepUnchanged = "unchanged"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+// stepResult is the outcome of a step.
+type stepResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// skipError is returned by a step that decided not to do anything.
+type skipError struct{ reason string }
+
+func (e *skipError) Error() string { return e.reason }
+
+// skipStep returns an error marking a step as skipped for reason.
+func skipStep(reason string) error { return &skipError{reason: reason} }
+
+// unchangedError is returned by a step that found its changes already in
+// place, typically from an earlier run.
+type unchangedError struct{ detail string }
+
+func (e *unchangedError) Error() string { return e.detail }
+
+// unchangedStep returns an error marking a step as already applied, with
+// detail describing the state it found.
+func unchangedStep(detail string) error { return &unchangedError{detail: detail} }
+
+// runSteps runs every step and collects their results.
+func runSteps(steps []step) []stepResult {
+	results := make([]stepResult, 0, len(steps))
+	for _, s := range steps {
+		detail, err := s.run()
+		result := stepResult{Name: s.name, Status: stepOK, Required: s.required, Detail: detail}
+		var skip *skipError
+		var unchanged *unchangedError
+		switch {
+		case errors.As(err, &skip):
+			result.Status = stepSkipped
+			result.Detail = skip.reason
+		case errors.As(err, &unchanged):
+			result.Status = stepUnchanged
+			result.Detail = unchanged.detail
+		case err != nil:
+			result.Status = stepFailed
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// failedRequiredSteps returns how many required steps failed.
+func failedRequiredSteps(results []stepResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Required && r.Status == stepFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// succeededStepDetail returns the detail of the step called name if it
+// succeeded, or an empty string.
+func succeededStepDetail(results []stepResult, name string) string {
+	for _, r := range results {
+		if r.Name == name && r.Status == stepOK {
+			return r.Detail
+		}
+	}
+	return ""
+}
+
+// printStepSummary prints a table with the outcome of each step.
+func printStepSummary(results []stepResult) {
+	if len(results) == 0 {
+		return
+	}
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+
+	fmt.Println("Post-create steps:")
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		// Pad before coloring so escape codes don't skew the columns
+		status := fmt.Sprintf("%-9s", r.Status)
+		switch r.Status {
+		case stepOK:
+			status = green(status)
+		case stepFailed:
+			status = paint(os.Stdout, "31", status)
+		}
+		detail := r.Detail
+		if r.Error != "" {
+			detail = r.Error
+		}
+		fmt.Printf("  %s  %-*s  %s\n", status, width, r.Name, detail)
+	}
+	fmt.Printf("  %d applied, %d already in place, %d skipped, %d failed\n", counts[stepOK], counts[stepUnchanged], counts[stepSkipped], counts[stepFailed])
+}
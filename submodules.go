@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// submodule is a submodule declared in .gitmodules.
+type submodule struct {
+	path string
+	// checkedOut is set if path holds a repository with a commit to
+	// reference.
+	checkedOut bool
+}
+
+// declaredSubmodules returns the submodules listed in .gitmodules, or nil if
+// there is no .gitmodules.
+func declaredSubmodules() ([]submodule, error) {
+	if _, err := os.Stat(".gitmodules"); err != nil {
+		return nil, nil
+	}
+	out, err := gitOutput("config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	if err != nil || out == "" {
+		// git exits non-zero when nothing matches
+		return nil, nil
+	}
+	var subs []submodule
+	for _, line := range strings.Split(out, "\n") {
+		_, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		path = filepath.ToSlash(filepath.Clean(path))
+		// Only the submodule's own repository counts, not the enclosing one
+		top, err := gitOutput("-C", path, "rev-parse", "--show-toplevel")
+		checkedOut := err == nil && sameFile(top, path) && gitCommand("-C", path, "rev-parse", "--verify", "-q", "HEAD").Run() == nil
+		subs = append(subs, submodule{path: path, checkedOut: checkedOut})
+	}
+	return subs, nil
+}
+
+// initSubmodules checks out the submodules whose commits the index already
+// references, as after cloning.
+func initSubmodules() error {
+	return runGit("submodule", "update", "--init", "--recursive")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// excludedTemplateFilesMessage is the message of the commit removing the
+// -template-exclude matches from the generated repository.
+const excludedTemplateFilesMessage = "Remove excluded template files"
+
+// createFromTemplate generates owner/name from the template repository tmpl
+// ("owner/repo"), taking name, description and visibility from spec. Like
+// Repositories.Create, an existing name is reported as a 422.
+func createFromTemplate(ctx context.Context, client *github.Client, tmpl, owner string, spec *github.Repository) (*github.Repository, *github.Response, error) {
+	tmplOwner, tmplRepo, _ := strings.Cut(tmpl, "/")
+	req := &github.TemplateRepoRequest{
+		Name:        spec.Name,
+		Owner:       github.String(owner),
+		Description: spec.Description,
+		Private:     spec.Private,
+	}
+	return client.Repositories.CreateFromTemplate(ctx, tmplOwner, tmplRepo, req)
+}
+
+// matchesExclude reports whether file, a slash-separated path relative to the
+// repository root, matches pattern: a glob against the whole path or the base
+// name, or a directory prefix.
+func matchesExclude(pattern, file string) bool {
+	if ok, _ := path.Match(pattern, file); ok {
+		return true
+	}
+	if ok, _ := path.Match(pattern, path.Base(file)); ok {
+		return true
+	}
+	dir := strings.TrimSuffix(pattern, "/")
+	return strings.HasPrefix(file, dir+"/")
+}
+
+// removeTemplateFiles removes the tracked files matching any of patterns from
+// the index and the working tree and returns them.
+func removeTemplateFiles(patterns []string) ([]string, error) {
+	out, err := gitOutput("ls-files")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var matched []string
+	for _, file := range strings.Split(out, "\n") {
+		for _, pattern := range patterns {
+			if matchesExclude(pattern, file) {
+				matched = append(matched, file)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	if err := runGit(append([]string{"rm", "-q", "--"}, matched...)...); err != nil {
+		return nil, fmt.Errorf("failed to remove excluded template files: %w", err)
+	}
+	return matched, nil
+}
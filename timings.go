@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// phaseTimings records how long each phase of a run takes. Each mark
+// attributes the time since the previous mark to the named phase, so phases
+// that are skipped simply don't show up.
+type phaseTimings struct {
+	last   time.Time
+	phases []phaseTiming
+}
+
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+}
+
+func newPhaseTimings() *phaseTimings {
+	return &phaseTimings{last: time.Now()}
+}
+
+// mark ends the phase called name.
+func (t *phaseTimings) mark(name string) {
+	now := time.Now()
+	t.phases = append(t.phases, phaseTiming{name: name, duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// print prints a table of the phases and their durations.
+func (t *phaseTimings) print() {
+	var total time.Duration
+	fmt.Println("Timings:")
+	for _, p := range t.phases {
+		fmt.Printf("  %-12s %8s\n", p.name, p.duration.Round(time.Millisecond))
+		total += p.duration
+	}
+	fmt.Printf("  %-12s %8s\n", "total", total.Round(time.Millisecond))
+}
+
+// milliseconds returns the phase durations in milliseconds for --json.
+func (t *phaseTimings) milliseconds() map[string]int64 {
+	ms := make(map[string]int64, len(t.phases))
+	for _, p := range t.phases {
+		ms[p.name] += p.duration.Milliseconds()
+	}
+	return ms
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// tryPassToken reads the token stored at path in the pass password store.
+// Only the first line is used, following pass's convention of keeping the
+// password on the first line and metadata below it.
+func tryPassToken(path string) (string, error) {
+	if _, err := exec.LookPath("pass"); err != nil {
+		return "", errors.New("pass is not installed")
+	}
+	out, err := exec.Command("pass", "show", path).Output()
+	if err != nil {
+		return "", fmt.Errorf("pass show %s failed: %w", path, err)
+	}
+	token, _, _ := strings.Cut(string(out), "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("pass entry %s is empty", path)
+	}
+	return token, nil
+}
+
+// tryOpToken reads the token at ref (an op://vault/item/field secret
+// reference) with the 1Password CLI.
+func tryOpToken(ref string) (string, error) {
+	if _, err := exec.LookPath("op"); err != nil {
+		return "", errors.New("the 1Password CLI (op) is not installed; see https://developer.1password.com/docs/cli/get-started/")
+	}
+	if !strings.HasPrefix(ref, "op://") {
+		return "", fmt.Errorf("invalid 1Password reference %q: expected op://vault/item/field", ref)
+	}
+
+	var stderr strings.Builder
+	cmd := exec.Command("op", "read", "--no-newline", ref)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not currently signed in") || strings.Contains(msg, "not signed in") {
+			return "", errors.New("the 1Password CLI is not signed in; run `op signin` (or enable the desktop app integration) and try again")
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("op read %s failed: %s", ref, msg)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("1Password item %s is empty", ref)
+	}
+	return token, nil
+}
+
+// tryGhStoredToken reads the token gh saved for host without running gh. gh
+// keeps it either in plain text in its hosts.yml or in the OS keyring under
+// the service "gh:<host>": the macOS Keychain (read with security) or the
+// Secret Service on Linux (read with secret-tool). It returns the token and
+// where it was found.
+func tryGhStoredToken(host string) (token, location string, err error) {
+	var tried []string
+
+	if dir, err := ghConfigDir(); err == nil {
+		path := filepath.Join(dir, "hosts.yml")
+		if token, err := ghHostsToken(path, host); err == nil {
+			return token, path, nil
+		}
+		tried = append(tried, path)
+	}
+
+	// The active account's token is the entry with an empty user name;
+	// other accounts have entries under their own names
+	service := "gh:" + host
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", "", "-w")
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "username", "")
+	}
+	if cmd != nil {
+		location := fmt.Sprintf("keyring entry %s", service)
+		if out, err := cmd.Output(); err == nil {
+			if token := decodeKeyringValue(strings.TrimSpace(string(out))); token != "" {
+				return token, location, nil
+			}
+		}
+		tried = append(tried, location)
+	}
+
+	if len(tried) == 0 {
+		return "", "", errors.New("no location to look for a gh token on this system")
+	}
+	return "", "", fmt.Errorf("no gh token for %s in %s", host, strings.Join(tried, " or "))
+}
+
+// decodeKeyringValue undoes the encoding go-keyring, which gh stores its
+// token with, applies to values in the macOS Keychain. A value that doesn't
+// decode is returned empty.
+func decodeKeyringValue(value string) string {
+	if encoded, ok := strings.CutPrefix(value, "go-keyring-base64:"); ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(decoded))
+	}
+	if encoded, ok := strings.CutPrefix(value, "go-keyring-encoded:"); ok {
+		decoded, err := hex.DecodeString(encoded)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(decoded))
+	}
+	return value
+}
+
+// ghConfigDir returns gh's configuration directory, following gh's own
+// lookup order.
+func ghConfigDir() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh"), nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh"), nil
+}
+
+// ghHostsToken returns the token of host's active account in gh's
+// hosts.yml. The file is simple enough that it's read line by line rather
+// than with a YAML parser: hosts are top-level keys, and below one the
+// active account is named by user. With several accounts, each one's token
+// is under users.<name>.oauth_token, and the oauth_token next to user is the
+// active one's; older files only have the latter.
+func ghHostsToken(path, host string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	inHost := false
+	hostIndent := -1 // indentation of host's own keys
+	var user, hostToken, section string
+	userTokens := make(map[string]string)
+	var account string // the users entry being read
+	accountIndent := -1
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			inHost = strings.TrimSpace(line) == host+":"
+			hostIndent = -1
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if hostIndent < 0 {
+			hostIndent = indent
+		}
+		switch {
+		case indent <= hostIndent:
+			section = key
+			account, accountIndent = "", -1
+			switch key {
+			case "user":
+				user = value
+			case "oauth_token":
+				hostToken = value
+			}
+		case section != "users":
+		case accountIndent < 0 || indent <= accountIndent:
+			account, accountIndent = key, indent
+		case key == "oauth_token" && value != "":
+			userTokens[account] = value
+		}
+	}
+	if token := userTokens[user]; user != "" && token != "" {
+		return token, nil
+	}
+	if hostToken != "" {
+		return hostToken, nil
+	}
+	return "", fmt.Errorf("no token for %s in %s", host, path)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// topicMarkers maps files that identify a language or tool to the topic
+// added for them by --auto-topics.
+var topicMarkers = []struct {
+	path  string
+	topic string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"package.json", "javascript"},
+	{"tsconfig.json", "typescript"},
+	{"pyproject.toml", "python"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"Gemfile", "ruby"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "kotlin"},
+	{"composer.json", "php"},
+	{"mix.exs", "elixir"},
+	{"Package.swift", "swift"},
+	{"Dockerfile", "docker"},
+	{"docker-compose.yml", "docker-compose"},
+	{"compose.yaml", "docker-compose"},
+	{".github/workflows", "github-actions"},
+	{"Chart.yaml", "helm"},
+	{"main.tf", "terraform"},
+}
+
+// detectTopics returns topics for the languages and tools found in the
+// current directory.
+func detectTopics() []string {
+	var topics []string
+	for _, m := range topicMarkers {
+		if _, err := os.Stat(m.path); err == nil {
+			topics = mergeTopics(topics, []string{m.topic})
+		}
+	}
+	return topics
+}
+
+// mergeTopics returns the union of a and b, keeping first-seen order.
+func mergeTopics(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, t := range append(append([]string{}, a...), b...) {
+		if !seen[t] {
+			seen[t] = true
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
+
+var topicPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// normalizeTopic lowercases topic and checks it against GitHub's rules:
+// lowercase letters, numbers and hyphens, at most 50 characters.
+func normalizeTopic(topic string) (string, error) {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if len(topic) > 50 || !topicPattern.MatchString(topic) {
+		return "", fmt.Errorf("invalid topic %q: topics must start with a letter or number, contain only lowercase letters, numbers and hyphens, and be at most 50 characters", topic)
+	}
+	return topic, nil
+}
+
+// readTopics reads a -topics-from file: one topic per line, blank lines and
+// lines starting with # ignored. Each topic is normalized like -topics.
+func readTopics(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var topics []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		topic, err := normalizeTopic(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		topics = mergeTopics(topics, []string{topic})
+	}
+	return topics, nil
+}
+
+// replaceTopics replaces the topics of owner/repo with topics and reports
+// whether that changed anything.
+func replaceTopics(ctx context.Context, client *github.Client, owner, repo string, topics []string) (bool, error) {
+	existing, _, err := client.Repositories.ListAllTopics(ctx, owner, repo)
+	if err != nil {
+		return false, fmt.Errorf("failed to get topics: %w", err)
+	}
+	if len(existing) == len(topics) && len(mergeTopics(existing, topics)) == len(existing) {
+		return false, nil
+	}
+	return true, setTopics(ctx, client, owner, repo, topics)
+}
+
+// languageTopicCount is how many of the top languages -auto-language-topics
+// adds.
+const languageTopicCount = 3
+
+// languagesTimeout bounds how long we wait for GitHub to analyze the
+// languages of freshly pushed code.
+const languagesTimeout = 30 * time.Second
+
+// languageTopicNames maps GitHub language names that don't lowercase into a
+// valid topic to the topic GitHub itself uses for them.
+var languageTopicNames = map[string]string{
+	"C++":        "cpp",
+	"C#":         "csharp",
+	"F#":         "fsharp",
+	"Vim Script": "vim",
+}
+
+// languageTopics returns topics for the top languages of owner/repo, by
+// bytes of code. Languages are computed after a push, so an empty result is
+// polled for up to languagesTimeout.
+func languageTopics(ctx context.Context, client *github.Client, owner, repo string) ([]string, error) {
+	deadline := time.Now().Add(languagesTimeout)
+	for {
+		languages, _, err := client.Repositories.ListLanguages(ctx, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list languages: %w", err)
+		}
+		if len(languages) > 0 {
+			return topLanguageTopics(languages, languageTopicCount), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// topLanguageTopics turns the n largest of languages (name to bytes) into
+// topics, skipping names that don't make a valid topic.
+func topLanguageTopics(languages map[string]int, n int) []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if languages[names[i]] != languages[names[j]] {
+			return languages[names[i]] > languages[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var topics []string
+	for _, name := range names {
+		if len(topics) == n {
+			break
+		}
+		topic, ok := languageTopicNames[name]
+		if !ok {
+			topic = strings.ReplaceAll(name, " ", "-")
+		}
+		if topic, err := normalizeTopic(topic); err == nil {
+			topics = mergeTopics(topics, []string{topic})
+		}
+	}
+	return topics
+}
+
+// setTopics replaces the topics of owner/repo with topics.
+func setTopics(ctx context.Context, client *github.Client, owner, repo string, topics []string) error {
+	if _, _, err := client.Repositories.ReplaceAllTopics(ctx, owner, repo, topics); err != nil {
+		return fmt.Errorf("failed to set topics: %w", err)
+	}
+	return nil
+}
+
+// addTopics adds topics to those owner/repo already has and returns the
+// resulting set and whether it changed.
+func addTopics(ctx context.Context, client *github.Client, owner, repo string, topics []string) ([]string, bool, error) {
+	existing, _, err := client.Repositories.ListAllTopics(ctx, owner, repo)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get topics: %w", err)
+	}
+	merged := mergeTopics(existing, topics)
+	if len(merged) == len(existing) {
+		return merged, false, nil
+	}
+	return merged, true, setTopics(ctx, client, owner, repo, merged)
+}